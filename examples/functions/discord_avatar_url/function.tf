@@ -0,0 +1,7 @@
+output "account_avatar" {
+  value = provider::grackdb::discord_avatar_url(
+    grackdb_discord_account.example.discord_id,
+    null,
+    grackdb_discord_account.example.discriminator,
+  )
+}
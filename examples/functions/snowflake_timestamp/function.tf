@@ -0,0 +1,3 @@
+output "account_created_at" {
+  value = provider::grackdb::snowflake_timestamp(grackdb_discord_account.example.discord_id)
+}
@@ -0,0 +1,3 @@
+output "account_mention" {
+  value = provider::grackdb::snowflake_to_mention(grackdb_discord_account.example.discord_id)
+}
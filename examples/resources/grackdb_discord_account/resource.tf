@@ -1,5 +1,5 @@
 resource "grackdb_discord_account" "example" {
-  discord_id    = "11111111111111111"
-  username      = "Example"
-  discriminator = "0001"
+  discord_id  = "11111111111111111"
+  username    = "example"
+  global_name = "Example"
 }
\ No newline at end of file
@@ -0,0 +1,230 @@
+// Package auth obtains GrackDB API tokens through an OAuth login flow, storing them in the
+// user's config directory where the provider looks for them by default.
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// ClientID identifies the provider to GrackDB's OAuth server. It's a public client, so has no secret
+// and relies on PKCE instead.
+const ClientID = "terraform-provider-grackdb"
+
+// oauthConfig returns the OAuth configuration for the GrackDB instance serving apiUrl, whose
+// authorization and token endpoints are on the same host.
+func oauthConfig(apiUrl string, redirectURL string) (*oauth2.Config, error) {
+	base, err := url.Parse(apiUrl)
+	if err != nil {
+		return nil, fmt.Errorf("invalid API URL: %w", err)
+	}
+
+	endpoint := func(path string) string {
+		return base.ResolveReference(&url.URL{Path: path}).String()
+	}
+
+	return &oauth2.Config{
+		ClientID:    ClientID,
+		RedirectURL: redirectURL,
+		Endpoint: oauth2.Endpoint{
+			AuthURL:   endpoint("/oauth/authorize"),
+			TokenURL:  endpoint("/oauth/token"),
+			AuthStyle: oauth2.AuthStyleInParams,
+		},
+	}, nil
+}
+
+// CredentialsPath returns the path of the file tokens obtained by logging in are stored in.
+func CredentialsPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, "grackdb", "credentials.json"), nil
+}
+
+// storedCredentials maps API URLs to the token obtained for them.
+type storedCredentials map[string]*oauth2.Token
+
+func readCredentials() (storedCredentials, error) {
+	path, err := CredentialsPath()
+	if err != nil {
+		return nil, err
+	}
+
+	contents, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return storedCredentials{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	creds := storedCredentials{}
+	if err := json.Unmarshal(contents, &creds); err != nil {
+		return nil, fmt.Errorf("unable to decode %s: %w", path, err)
+	}
+
+	return creds, nil
+}
+
+// saveToken stores token as the credentials for apiUrl, keeping those for other instances.
+// The caller must hold the lock returned by lockCredentials.
+func saveToken(apiUrl string, token *oauth2.Token) error {
+	path, err := CredentialsPath()
+	if err != nil {
+		return err
+	}
+
+	creds, err := readCredentials()
+	if err != nil {
+		return err
+	}
+	creds[apiUrl] = token
+
+	contents, err := json.MarshalIndent(creds, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(path, contents)
+}
+
+// writeFileAtomic replaces the file at path with contents, so concurrent readers see either
+// the old or new contents rather than a partially written file.
+func writeFileAtomic(path string, contents []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	if _, err := file.Write(contents); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(file.Name(), path)
+}
+
+const (
+	// lockRetryInterval is how often a held lock on the credentials file is checked again.
+	lockRetryInterval = 50 * time.Millisecond
+
+	// staleLockAge is how long a lock is held before it's assumed to have been left behind by
+	// a process that exited without releasing it.
+	staleLockAge = 30 * time.Second
+)
+
+// lockCredentials takes an exclusive lock on the credentials file, shared between every
+// process using it, returning a function releasing it. It serialises refreshes, which would
+// otherwise spend the same refresh token from several provider processes at once.
+func lockCredentials(ctx context.Context) (func(), error) {
+	path, err := CredentialsPath()
+	if err != nil {
+		return nil, err
+	}
+	lockPath := path + ".lock"
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, err
+	}
+
+	for {
+		file, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+		if err == nil {
+			file.Close()
+			return func() { os.Remove(lockPath) }, nil
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return nil, fmt.Errorf("unable to lock credentials: %w", err)
+		}
+
+		if info, err := os.Stat(lockPath); err == nil && time.Since(info.ModTime()) > staleLockAge {
+			os.Remove(lockPath)
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("waiting for lock on credentials: %w", ctx.Err())
+		case <-time.After(lockRetryInterval):
+		}
+	}
+}
+
+// Token returns the stored access token for apiUrl, refreshing and storing it again if it has
+// expired. An empty token is returned if the user hasn't logged in to apiUrl. The token is
+// refreshed with the *http.Client stored in ctx under oauth2.HTTPClient, if there is one.
+func Token(ctx context.Context, apiUrl string) (string, error) {
+	if _, err := CredentialsPath(); err != nil {
+		// Without a config directory there can't be any stored credentials.
+		return "", nil
+	}
+
+	stored, err := storedToken(apiUrl)
+	if err != nil || stored == nil {
+		return "", err
+	}
+	if stored.Valid() {
+		return stored.AccessToken, nil
+	}
+
+	unlock, err := lockCredentials(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer unlock()
+
+	// Another process may have refreshed the token while this one waited for the lock.
+	stored, err = storedToken(apiUrl)
+	if err != nil || stored == nil {
+		return "", err
+	}
+	if stored.Valid() {
+		return stored.AccessToken, nil
+	}
+
+	config, err := oauthConfig(apiUrl, "")
+	if err != nil {
+		return "", err
+	}
+
+	token, err := config.TokenSource(ctx, stored).Token()
+	if err != nil {
+		return "", fmt.Errorf("unable to refresh stored token, run `terraform-provider-grackdb login` again: %w", err)
+	}
+
+	if err := saveToken(apiUrl, token); err != nil {
+		return "", fmt.Errorf("unable to store refreshed token: %w", err)
+	}
+
+	return token.AccessToken, nil
+}
+
+// storedToken returns the token stored for apiUrl, or nil if there isn't one.
+func storedToken(apiUrl string) (*oauth2.Token, error) {
+	creds, err := readCredentials()
+	if err != nil {
+		return nil, err
+	}
+
+	return creds[apiUrl], nil
+}
@@ -0,0 +1,282 @@
+package auth
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// oauthServer is a fake GrackDB OAuth server. It issues a token for the authorization code
+// "code", and rotates refresh tokens, rejecting any that have already been used.
+type oauthServer struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	refreshes int
+	issued    int
+	valid     map[string]bool
+}
+
+func newOAuthServer(t *testing.T) *oauthServer {
+	s := &oauthServer{valid: map[string]bool{}}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveToken))
+	t.Cleanup(s.Close)
+
+	return s
+}
+
+func (s *oauthServer) apiUrl() string {
+	return s.URL + "/query"
+}
+
+func (s *oauthServer) serveToken(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/oauth/token" {
+		http.NotFound(w, r)
+		return
+	}
+	r.ParseForm()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if r.Form.Get("client_id") != ClientID {
+		http.Error(w, `{"error":"invalid_client"}`, http.StatusUnauthorized)
+		return
+	}
+
+	switch r.Form.Get("grant_type") {
+	case "authorization_code":
+		if r.Form.Get("code") != "code" || r.Form.Get("code_verifier") == "" {
+			http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+			return
+		}
+	case "refresh_token":
+		refreshToken := r.Form.Get("refresh_token")
+		if !s.valid[refreshToken] {
+			http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+			return
+		}
+		delete(s.valid, refreshToken)
+		s.refreshes++
+	default:
+		http.Error(w, `{"error":"unsupported_grant_type"}`, http.StatusBadRequest)
+		return
+	}
+
+	s.issued++
+	refreshToken := fmt.Sprintf("refresh-%d", s.issued)
+	s.valid[refreshToken] = true
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"access_token":  fmt.Sprintf("access-%d", s.issued),
+		"refresh_token": refreshToken,
+		"token_type":    "Bearer",
+		"expires_in":    3600,
+	})
+}
+
+// isolateConfigDir points the user's config directory at an empty temporary directory.
+func isolateConfigDir(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	t.Setenv("AppData", dir)
+}
+
+// storeExpiredToken stores a token for apiUrl that has to be refreshed before use.
+func storeExpiredToken(t *testing.T, server *oauthServer) {
+	t.Helper()
+
+	server.mu.Lock()
+	server.valid["refresh-0"] = true
+	server.mu.Unlock()
+
+	err := saveToken(server.apiUrl(), &oauth2.Token{
+		AccessToken:  "access-0",
+		RefreshToken: "refresh-0",
+		Expiry:       time.Now().Add(-time.Hour),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// startLogin runs Login in the background, returning the authorization URL it prints and a
+// channel receiving its result.
+func startLogin(t *testing.T, ctx context.Context, apiUrl string) (*url.URL, <-chan error) {
+	t.Helper()
+
+	reader, writer := io.Pipe()
+	result := make(chan error, 1)
+	go func() {
+		err := Login(ctx, apiUrl, "discord", writer)
+		writer.Close()
+		result <- err
+	}()
+
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "http") {
+			go io.Copy(io.Discard, reader)
+
+			authURL, err := url.Parse(line)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return authURL, result
+		}
+	}
+
+	t.Fatalf("Login didn't print an authorization URL: %v", <-result)
+	return nil, nil
+}
+
+func TestLogin(t *testing.T) {
+	isolateConfigDir(t)
+	server := newOAuthServer(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	authURL, result := startLogin(t, ctx, server.apiUrl())
+
+	query := authURL.Query()
+	if authURL.Path != "/oauth/authorize" || query.Get("client_id") != ClientID || query.Get("provider") != "discord" {
+		t.Errorf("unexpected authorization URL %s", authURL)
+	}
+	if query.Get("code_challenge") == "" || query.Get("code_challenge_method") != "S256" {
+		t.Errorf("authorization URL %s doesn't use PKCE", authURL)
+	}
+
+	callback := query.Get("redirect_uri") + "?" + url.Values{"code": {"code"}, "state": {query.Get("state")}}.Encode()
+	resp, err := http.Get(callback)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("callback returned status %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+
+	if err := <-result; err != nil {
+		t.Fatalf("Login failed: %s", err)
+	}
+
+	token, err := Token(ctx, server.apiUrl())
+	if err != nil || token != "access-1" {
+		t.Errorf("Token() = %q, %v, want the token obtained by logging in", token, err)
+	}
+	if token, err := Token(ctx, server.URL+"/other"); err != nil || token != "" {
+		t.Errorf("Token() for another API URL = %q, %v, want none", token, err)
+	}
+}
+
+func TestLoginCallbackState(t *testing.T) {
+	isolateConfigDir(t)
+	server := newOAuthServer(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	authURL, result := startLogin(t, ctx, server.apiUrl())
+
+	callback := authURL.Query().Get("redirect_uri") + "?" + url.Values{"code": {"code"}, "state": {"forged"}}.Encode()
+	resp, err := http.Get(callback)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("callback returned status %d, want %d", resp.StatusCode, http.StatusBadRequest)
+	}
+
+	if err := <-result; err == nil || !strings.Contains(err.Error(), "state") {
+		t.Errorf("Login error = %v, want a state mismatch", err)
+	}
+	if token, err := Token(ctx, server.apiUrl()); err != nil || token != "" {
+		t.Errorf("Token() = %q, %v, want no token stored", token, err)
+	}
+}
+
+func TestTokenRefresh(t *testing.T) {
+	isolateConfigDir(t)
+	server := newOAuthServer(t)
+	storeExpiredToken(t, server)
+
+	token, err := Token(context.Background(), server.apiUrl())
+	if err != nil || token != "access-1" {
+		t.Fatalf("Token() = %q, %v, want the refreshed token", token, err)
+	}
+
+	creds, err := readCredentials()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored := creds[server.apiUrl()]; stored.AccessToken != "access-1" || stored.RefreshToken != "refresh-1" {
+		t.Errorf("stored token = %+v, want the refreshed token", stored)
+	}
+
+	// The refreshed token is valid, so is used without refreshing again.
+	if token, err := Token(context.Background(), server.apiUrl()); err != nil || token != "access-1" {
+		t.Errorf("Token() = %q, %v, want the refreshed token", token, err)
+	}
+	if server.refreshes != 1 {
+		t.Errorf("refreshed %d times, want 1", server.refreshes)
+	}
+}
+
+func TestTokenConcurrentRefresh(t *testing.T) {
+	isolateConfigDir(t)
+	server := newOAuthServer(t)
+	storeExpiredToken(t, server)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			token, err := Token(context.Background(), server.apiUrl())
+			if err != nil || token != "access-1" {
+				t.Errorf("Token() = %q, %v, want the refreshed token", token, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if server.refreshes != 1 {
+		t.Errorf("refreshed %d times, want 1", server.refreshes)
+	}
+}
+
+func TestTokenRefreshHTTPClient(t *testing.T) {
+	isolateConfigDir(t)
+
+	server := &oauthServer{valid: map[string]bool{}}
+	server.Server = httptest.NewTLSServer(http.HandlerFunc(server.serveToken))
+	t.Cleanup(server.Close)
+	storeExpiredToken(t, server)
+
+	// The default client doesn't trust the test server's certificate.
+	if _, err := Token(context.Background(), server.apiUrl()); err == nil {
+		t.Fatal("Token() refreshed against an untrusted server without the configured client")
+	}
+
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, server.Client())
+	if token, err := Token(ctx, server.apiUrl()); err != nil || token != "access-1" {
+		t.Errorf("Token() = %q, %v, want the token refreshed with the configured client", token, err)
+	}
+}
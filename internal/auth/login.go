@@ -0,0 +1,123 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"net"
+	"net/http"
+
+	"golang.org/x/oauth2"
+)
+
+// callbackPath is the path of the local server the OAuth flow redirects back to.
+const callbackPath = "/callback"
+
+type callbackResult struct {
+	code string
+	err  error
+}
+
+// Login runs an OAuth authorization code flow against the GrackDB instance serving apiUrl,
+// receiving the redirect on a local server, and stores the resulting token. identityProvider
+// optionally selects the service to log in with, such as discord or github. The code is
+// exchanged with the *http.Client stored in ctx under oauth2.HTTPClient, if there is one.
+func Login(ctx context.Context, apiUrl string, identityProvider string, out io.Writer) error {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return fmt.Errorf("unable to start callback server: %w", err)
+	}
+
+	config, err := oauthConfig(apiUrl, "http://"+listener.Addr().String()+callbackPath)
+	if err != nil {
+		listener.Close()
+		return err
+	}
+
+	state, err := randomState()
+	if err != nil {
+		listener.Close()
+		return err
+	}
+	verifier := oauth2.GenerateVerifier()
+
+	options := []oauth2.AuthCodeOption{oauth2.S256ChallengeOption(verifier)}
+	if identityProvider != "" {
+		options = append(options, oauth2.SetAuthURLParam("provider", identityProvider))
+	}
+
+	results := make(chan callbackResult, 1)
+	mux := http.NewServeMux()
+	mux.HandleFunc(callbackPath, func(w http.ResponseWriter, r *http.Request) {
+		result := callbackResult{code: r.URL.Query().Get("code")}
+		switch {
+		case r.URL.Query().Get("state") != state:
+			result.err = errors.New("OAuth callback state did not match")
+		case r.URL.Query().Get("error") != "":
+			result.err = fmt.Errorf("GrackDB denied the login: %s", r.URL.Query().Get("error"))
+		case result.code == "":
+			result.err = errors.New("OAuth callback did not include a code")
+		}
+
+		if result.err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(w, "Login failed: %s", html.EscapeString(result.err.Error()))
+		} else {
+			fmt.Fprint(w, "Logged in to GrackDB, you can close this window.")
+		}
+
+		select {
+		case results <- result:
+		default:
+		}
+	})
+
+	server := &http.Server{Handler: mux}
+	go server.Serve(listener)
+	defer server.Close()
+
+	fmt.Fprintf(out, "Open the following URL in your browser to log in to GrackDB:\n\n    %s\n\n", config.AuthCodeURL(state, options...))
+
+	var result callbackResult
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case result = <-results:
+	}
+	if result.err != nil {
+		return result.err
+	}
+
+	token, err := config.Exchange(ctx, result.code, oauth2.VerifierOption(verifier))
+	if err != nil {
+		return fmt.Errorf("unable to exchange authorization code: %w", err)
+	}
+
+	unlock, err := lockCredentials(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if err := saveToken(apiUrl, token); err != nil {
+		return fmt.Errorf("unable to store token: %w", err)
+	}
+
+	path, _ := CredentialsPath()
+	fmt.Fprintf(out, "Logged in, token stored in %s.\n", path)
+
+	return nil
+}
+
+func randomState() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
@@ -0,0 +1,76 @@
+// Package discord contains helpers for working with Discord identifiers and CDN URLs.
+package discord
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Epoch is the first millisecond of 2015 as a Unix millisecond timestamp, which Discord snowflake
+// timestamps are relative to.
+const Epoch int64 = 1420070400000
+
+// CDNURL is the base URL Discord serves user avatars from.
+const CDNURL = "https://cdn.discordapp.com"
+
+// ParseSnowflake parses a Discord snowflake from its string representation.
+func ParseSnowflake(snowflake string) (uint64, error) {
+	id, err := strconv.ParseUint(snowflake, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a valid Discord snowflake", snowflake)
+	}
+
+	return id, nil
+}
+
+// SnowflakeTimestamp returns the time at which the given snowflake was created.
+func SnowflakeTimestamp(snowflake string) (time.Time, error) {
+	id, err := ParseSnowflake(snowflake)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return time.UnixMilli(int64(id>>22) + Epoch).UTC(), nil
+}
+
+// UserMention returns the message syntax mentioning the user with the given snowflake.
+func UserMention(snowflake string) (string, error) {
+	id, err := ParseSnowflake(snowflake)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("<@%d>", id), nil
+}
+
+// AvatarURL returns the CDN URL for a user's avatar.
+// When avatarHash is empty the URL of the default avatar Discord assigns to the user is returned instead,
+// using discriminator to select it for accounts that have not migrated to unique usernames.
+func AvatarURL(snowflake string, avatarHash string, discriminator string) (string, error) {
+	id, err := ParseSnowflake(snowflake)
+	if err != nil {
+		return "", err
+	}
+
+	if avatarHash != "" {
+		extension := "png"
+		if strings.HasPrefix(avatarHash, "a_") {
+			extension = "gif"
+		}
+
+		return fmt.Sprintf("%s/avatars/%d/%s.%s", CDNURL, id, avatarHash, extension), nil
+	}
+
+	index := (id >> 22) % 6
+	if discriminator != "" && discriminator != "0" {
+		discriminatorVal, err := strconv.ParseUint(discriminator, 10, 64)
+		if err != nil {
+			return "", fmt.Errorf("%q is not a valid Discord discriminator", discriminator)
+		}
+		index = discriminatorVal % 5
+	}
+
+	return fmt.Sprintf("%s/embed/avatars/%d.png", CDNURL, index), nil
+}
@@ -0,0 +1,168 @@
+package discord
+
+import (
+	"testing"
+	"time"
+)
+
+// exampleSnowflake is the snowflake used in Discord's API documentation.
+const exampleSnowflake = "175928847299117063"
+
+func TestParseSnowflake(t *testing.T) {
+	tests := []struct {
+		name      string
+		snowflake string
+		want      uint64
+		wantErr   bool
+	}{
+		{name: "valid", snowflake: exampleSnowflake, want: 175928847299117063},
+		{name: "zero", snowflake: "0", want: 0},
+		{name: "max", snowflake: "18446744073709551615", want: 18446744073709551615},
+		{name: "empty", snowflake: "", wantErr: true},
+		{name: "non-numeric", snowflake: "not-a-snowflake", wantErr: true},
+		{name: "negative", snowflake: "-1", wantErr: true},
+		{name: "overflow", snowflake: "18446744073709551616", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseSnowflake(tt.snowflake)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseSnowflake(%q) error = %v, wantErr %v", tt.snowflake, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseSnowflake(%q) = %d, want %d", tt.snowflake, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSnowflakeTimestamp(t *testing.T) {
+	tests := []struct {
+		name      string
+		snowflake string
+		want      time.Time
+		wantErr   bool
+	}{
+		{name: "example", snowflake: exampleSnowflake, want: time.Date(2016, time.April, 30, 11, 18, 25, 796*int(time.Millisecond), time.UTC)},
+		{name: "epoch", snowflake: "0", want: time.UnixMilli(Epoch).UTC()},
+		{name: "invalid", snowflake: "abc", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := SnowflakeTimestamp(tt.snowflake)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SnowflakeTimestamp(%q) error = %v, wantErr %v", tt.snowflake, err, tt.wantErr)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("SnowflakeTimestamp(%q) = %s, want %s", tt.snowflake, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUserMention(t *testing.T) {
+	tests := []struct {
+		name      string
+		snowflake string
+		want      string
+		wantErr   bool
+	}{
+		{name: "valid", snowflake: exampleSnowflake, want: "<@175928847299117063>"},
+		{name: "leading zeros", snowflake: "0042", want: "<@42>"},
+		{name: "invalid", snowflake: "<@175928847299117063>", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := UserMention(tt.snowflake)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("UserMention(%q) error = %v, wantErr %v", tt.snowflake, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("UserMention(%q) = %q, want %q", tt.snowflake, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAvatarURL(t *testing.T) {
+	tests := []struct {
+		name          string
+		snowflake     string
+		avatarHash    string
+		discriminator string
+		want          string
+		wantErr       bool
+	}{
+		{
+			name:       "png hash",
+			snowflake:  exampleSnowflake,
+			avatarHash: "8342729096ea3675442027381ff50dfe",
+			want:       CDNURL + "/avatars/175928847299117063/8342729096ea3675442027381ff50dfe.png",
+		},
+		{
+			name:       "animated hash",
+			snowflake:  exampleSnowflake,
+			avatarHash: "a_8342729096ea3675442027381ff50dfe",
+			want:       CDNURL + "/avatars/175928847299117063/a_8342729096ea3675442027381ff50dfe.gif",
+		},
+		{
+			name:          "hash ignores discriminator",
+			snowflake:     exampleSnowflake,
+			avatarHash:    "8342729096ea3675442027381ff50dfe",
+			discriminator: "not-a-discriminator",
+			want:          CDNURL + "/avatars/175928847299117063/8342729096ea3675442027381ff50dfe.png",
+		},
+		{
+			// (175928847299117063 >> 22) % 6 == 2
+			name:      "migrated default",
+			snowflake: exampleSnowflake,
+			want:      CDNURL + "/embed/avatars/2.png",
+		},
+		{
+			name:          "migrated default with zero discriminator",
+			snowflake:     exampleSnowflake,
+			discriminator: "0",
+			want:          CDNURL + "/embed/avatars/2.png",
+		},
+		{
+			// 1339 % 5 == 4, which differs from the migrated default.
+			name:          "legacy default",
+			snowflake:     exampleSnowflake,
+			discriminator: "1339",
+			want:          CDNURL + "/embed/avatars/4.png",
+		},
+		{
+			name:          "legacy default with leading zeros",
+			snowflake:     exampleSnowflake,
+			discriminator: "0005",
+			want:          CDNURL + "/embed/avatars/0.png",
+		},
+		{
+			name:          "bad discriminator",
+			snowflake:     exampleSnowflake,
+			discriminator: "abcd",
+			wantErr:       true,
+		},
+		{
+			name:       "bad snowflake",
+			snowflake:  "abc",
+			avatarHash: "8342729096ea3675442027381ff50dfe",
+			wantErr:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := AvatarURL(tt.snowflake, tt.avatarHash, tt.discriminator)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("AvatarURL(%q, %q, %q) error = %v, wantErr %v", tt.snowflake, tt.avatarHash, tt.discriminator, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("AvatarURL(%q, %q, %q) = %q, want %q", tt.snowflake, tt.avatarHash, tt.discriminator, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package provider
+
+import (
+	"bytes"
+	"encoding/json"
+	"sync"
+)
+
+type cacheKey struct {
+	typename string
+	id       string
+}
+
+// recordCache holds every record returned by the API during a single provider run, keyed by
+// GraphQL type and ID, so repeated lookups of the same record don't each cost a request.
+// Only objects selected through the shared fragments, which include __typename, are cached as
+// these are known to contain every field a resource exposes.
+type recordCache struct {
+	mu         sync.Mutex
+	records    map[cacheKey]json.RawMessage
+	generation uint64
+}
+
+func newRecordCache() *recordCache {
+	return &recordCache{records: map[cacheKey]json.RawMessage{}}
+}
+
+func (c *recordCache) get(typename string, id string) (json.RawMessage, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	record, ok := c.records[cacheKey{typename: typename, id: id}]
+	return record, ok
+}
+
+// currentGeneration returns a token to pass to populate once a response has been received,
+// ensuring responses to requests that raced with a mutation aren't cached.
+func (c *recordCache) currentGeneration() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.generation
+}
+
+// populate caches every complete record found in the data portion of a response.
+func (c *recordCache) populate(generation uint64, data json.RawMessage) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var decoded interface{}
+	if err := decoder.Decode(&decoded); err != nil {
+		return
+	}
+
+	records := map[cacheKey]json.RawMessage{}
+	collectRecords(decoded, records)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if generation != c.generation {
+		return
+	}
+	for k, v := range records {
+		c.records[k] = v
+	}
+}
+
+// invalidate drops every cached record. It's called after any mutation, as a change to one
+// record can be reflected in others, such as the owner of a Discord account.
+func (c *recordCache) invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.records = map[cacheKey]json.RawMessage{}
+	c.generation++
+}
+
+func collectRecords(value interface{}, records map[cacheKey]json.RawMessage) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		typename, hasTypename := v["__typename"].(string)
+		id, hasID := v["id"].(string)
+		if hasTypename && hasID {
+			if encoded, err := json.Marshal(v); err == nil {
+				records[cacheKey{typename: typename, id: id}] = encoded
+			}
+		}
+
+		for _, child := range v {
+			collectRecords(child, records)
+		}
+	case []interface{}:
+		for _, child := range v {
+			collectRecords(child, records)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const discordAccountResponse = `{
+	"discordAccount": {
+		"__typename": "DiscordAccount",
+		"id": "d1",
+		"username": "bob",
+		"owner": {
+			"__typename": "User",
+			"id": "u1",
+			"username": "bob",
+			"avatarUrl": null
+		},
+		"bot": {
+			"id": "b1"
+		}
+	}
+}`
+
+func TestRecordCachePopulate(t *testing.T) {
+	cache := newRecordCache()
+	cache.populate(cache.currentGeneration(), json.RawMessage(discordAccountResponse))
+
+	tests := []struct {
+		name     string
+		typename string
+		id       string
+		want     map[string]interface{}
+	}{
+		{name: "top level", typename: "DiscordAccount", id: "d1", want: map[string]interface{}{"username": "bob"}},
+		{name: "nested owner", typename: "User", id: "u1", want: map[string]interface{}{"username": "bob", "avatarUrl": nil}},
+		{name: "without typename", typename: "Bot", id: "b1"},
+		{name: "wrong type", typename: "User", id: "d1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			record, ok := cache.get(tt.typename, tt.id)
+			if ok != (tt.want != nil) {
+				t.Fatalf("get(%q, %q) found = %v, want %v", tt.typename, tt.id, ok, tt.want != nil)
+			}
+			if !ok {
+				return
+			}
+
+			var decoded map[string]interface{}
+			if err := json.Unmarshal(record, &decoded); err != nil {
+				t.Fatalf("cached record isn't valid JSON: %s", err)
+			}
+			for field, want := range tt.want {
+				if got := decoded[field]; got != want {
+					t.Errorf("cached %s = %v, want %v", field, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestRecordCacheInvalidate(t *testing.T) {
+	cache := newRecordCache()
+	cache.populate(cache.currentGeneration(), json.RawMessage(discordAccountResponse))
+
+	cache.invalidate()
+
+	if _, ok := cache.get("DiscordAccount", "d1"); ok {
+		t.Error("record still cached after invalidation")
+	}
+	if _, ok := cache.get("User", "u1"); ok {
+		t.Error("nested record still cached after invalidation")
+	}
+
+	cache.populate(cache.currentGeneration(), json.RawMessage(discordAccountResponse))
+	if _, ok := cache.get("DiscordAccount", "d1"); !ok {
+		t.Error("record not cached from a response received after invalidation")
+	}
+}
+
+func TestRecordCacheRacedMutation(t *testing.T) {
+	cache := newRecordCache()
+
+	// A read is sent, then a mutation completes before its response arrives.
+	generation := cache.currentGeneration()
+	cache.invalidate()
+	cache.populate(generation, json.RawMessage(discordAccountResponse))
+
+	if _, ok := cache.get("DiscordAccount", "d1"); ok {
+		t.Error("cached a response that raced with a mutation")
+	}
+	if _, ok := cache.get("User", "u1"); ok {
+		t.Error("cached a nested record from a response that raced with a mutation")
+	}
+}
+
+func TestExecuteCachesUntilMutation(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload struct {
+			OperationName string `json:"operationName"`
+		}
+		json.NewDecoder(r.Body).Decode(&payload)
+
+		if payload.OperationName == "TestDeleteUser" {
+			w.Write([]byte(`{"data": {"deleteUser": {"id": "u1"}}}`))
+			return
+		}
+		w.Write([]byte(`{"data": ` + discordAccountResponse + `}`))
+	}))
+	defer server.Close()
+
+	client := &apiClient{
+		httpClient:  server.Client(),
+		apiUrl:      server.URL,
+		credentials: &credentials{},
+		cache:       newRecordCache(),
+	}
+
+	read := &operation{name: "TestReadDiscordAccount", document: `query TestReadDiscordAccount { discordAccount { id } }`}
+	var readData interface{}
+	if err := client.execute(context.Background(), read, nil, &readData); err != nil {
+		t.Fatalf("query failed: %s", err)
+	}
+	if _, ok := client.cache.get("User", "u1"); !ok {
+		t.Fatal("nested owner not cached after query")
+	}
+
+	mutation := &operation{name: "TestDeleteUser", document: `mutation TestDeleteUser { deleteUser { id } }`}
+	var mutationData interface{}
+	if err := client.execute(context.Background(), mutation, nil, &mutationData); err != nil {
+		t.Fatalf("mutation failed: %s", err)
+	}
+	if _, ok := client.cache.get("User", "u1"); ok {
+		t.Error("record still cached after mutation")
+	}
+	if _, ok := client.cache.get("DiscordAccount", "d1"); ok {
+		t.Error("record still cached after mutation")
+	}
+}
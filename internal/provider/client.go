@@ -0,0 +1,239 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// apiLogSubsystem is the tflog subsystem API calls are logged under, its level can be tuned
+// separately with TF_LOG_PROVIDER_GRACKDB_API.
+const apiLogSubsystem = "api"
+
+const apiLogLevelEnv = "TF_LOG_PROVIDER_GRACKDB_API"
+
+// persistedQueryNotFound is the error returned by the server when it doesn't recognise the
+// hash of a persisted query, in which case the full document must be sent.
+const persistedQueryNotFound = "PersistedQueryNotFound"
+
+// sensitiveVariables lists GraphQL variable names whose values are masked when logged.
+var sensitiveVariables = map[string]bool{
+	"token":        true,
+	"password":     true,
+	"secret":       true,
+	"clientSecret": true,
+	"refreshToken": true,
+}
+
+type graphqlError struct {
+	Message string        `json:"message"`
+	Path    []interface{} `json:"path"`
+}
+
+type graphqlResp struct {
+	Data   json.RawMessage `json:"data"`
+	Errors []graphqlError  `json:"errors"`
+}
+
+func (r *graphqlResp) hasError(message string) bool {
+	for _, e := range r.Errors {
+		if e.Message == message {
+			return true
+		}
+	}
+	return false
+}
+
+// execute sends op to the configured API, decoding the data portion of the response into data.
+func (c *apiClient) execute(ctx context.Context, op *operation, variables map[string]interface{}, data interface{}) error {
+	if variables == nil {
+		variables = map[string]interface{}{}
+	}
+
+	ctx = tflog.NewSubsystem(ctx, apiLogSubsystem, tflog.WithLevelFromEnv(apiLogLevelEnv))
+	if token := c.credentials.current(); token != "" {
+		ctx = tflog.SubsystemMaskAllFieldValuesStrings(ctx, apiLogSubsystem, token)
+	}
+	ctx = tflog.SubsystemSetField(ctx, apiLogSubsystem, "graphql_operation", op.name)
+
+	tflog.SubsystemDebug(ctx, apiLogSubsystem, "Sending GraphQL request", map[string]interface{}{
+		"graphql_variables": maskVariables(variables),
+	})
+
+	mutation := op.isMutation()
+	if mutation && c.readOnly {
+		return fmt.Errorf("refusing to send mutation %s as the provider is configured with read_only = true", op.name)
+	}
+
+	cacheGeneration := c.cache.currentGeneration()
+	if mutation {
+		// Invalidate once the mutation has completed too, whether or not it succeeded, as it
+		// may still have been applied.
+		defer c.cache.invalidate()
+	}
+
+	payload := map[string]interface{}{
+		"operationName": op.name,
+		"query":         op.document,
+		"variables":     variables,
+	}
+
+	if c.persistedQueries {
+		payload["extensions"] = map[string]interface{}{
+			"persistedQuery": map[string]interface{}{
+				"version":    1,
+				"sha256Hash": op.hash,
+			},
+		}
+		delete(payload, "query")
+	}
+
+	respData, statusCode, err := c.post(ctx, payload)
+	if err != nil {
+		return err
+	}
+
+	if c.persistedQueries && respData.hasError(persistedQueryNotFound) {
+		tflog.SubsystemDebug(ctx, apiLogSubsystem, "Persisted query not found, resending with the full document")
+
+		payload["query"] = op.document
+		respData, statusCode, err = c.post(ctx, payload)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(respData.Errors) != 0 {
+		messages := make([]string, 0, len(respData.Errors))
+		for _, e := range respData.Errors {
+			messages = append(messages, e.Message)
+		}
+		return fmt.Errorf("GrackDB returned errors: %s", strings.Join(messages, "; "))
+	}
+
+	if len(respData.Data) == 0 {
+		return fmt.Errorf("GrackDB returned no data (status %d)", statusCode)
+	}
+
+	err = json.Unmarshal(respData.Data, data)
+	if err != nil {
+		return fmt.Errorf("unable to decode response data: %w", err)
+	}
+
+	if !mutation {
+		c.cache.populate(cacheGeneration, respData.Data)
+	}
+
+	return nil
+}
+
+// post sends a single GraphQL request body to the configured API, returning the decoded response
+// and its HTTP status code. If the token is rejected and can be read again from its source, the
+// request is retried once with the new token.
+func (c *apiClient) post(ctx context.Context, payload map[string]interface{}) (*graphqlResp, int, error) {
+	reqBody, err := json.Marshal(payload)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	token := c.credentials.current()
+	respData, statusCode, err := c.send(ctx, reqBody, token)
+	if statusCode != http.StatusUnauthorized {
+		return respData, statusCode, err
+	}
+
+	reloaded, reloadErr := c.credentials.reload(ctx, token)
+	if reloadErr != nil {
+		return nil, statusCode, fmt.Errorf("API token was rejected and could not be read again: %w", reloadErr)
+	}
+	if !reloaded {
+		return respData, statusCode, err
+	}
+
+	tflog.SubsystemDebug(ctx, apiLogSubsystem, "API token was rejected, retrying with the token read again from its source", map[string]interface{}{
+		"token_source": c.credentials.source,
+	})
+	return c.send(ctx, reqBody, c.credentials.current())
+}
+
+// send makes a single HTTP request to the configured API authenticated with token.
+func (c *apiClient) send(ctx context.Context, reqBody []byte, token string) (*graphqlResp, int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.apiUrl, bytes.NewBuffer(reqBody))
+	if err != nil {
+		return nil, 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	if c.limiter != nil {
+		waitStart := time.Now()
+		if err := c.limiter.Wait(ctx); err != nil {
+			return nil, 0, fmt.Errorf("waiting for rate limiter: %w", err)
+		}
+		tflog.SubsystemTrace(ctx, apiLogSubsystem, "Waited for rate limiter", map[string]interface{}{
+			"wait_ms": time.Since(waitStart).Milliseconds(),
+		})
+	}
+
+	start := time.Now()
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		tflog.SubsystemError(ctx, apiLogSubsystem, "GraphQL request failed", map[string]interface{}{
+			"duration_ms": time.Since(start).Milliseconds(),
+			"error":       err.Error(),
+		})
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, resp.StatusCode, err
+	}
+
+	respData := new(graphqlResp)
+	err = json.Unmarshal(body, respData)
+
+	tflog.SubsystemDebug(ctx, apiLogSubsystem, "Received GraphQL response", map[string]interface{}{
+		"duration_ms":         time.Since(start).Milliseconds(),
+		"status_code":         resp.StatusCode,
+		"request_bytes":       len(reqBody),
+		"graphql_error_count": len(respData.Errors),
+	})
+	tflog.SubsystemTrace(ctx, apiLogSubsystem, "GraphQL response body", map[string]interface{}{
+		"body": string(body),
+	})
+
+	if err != nil {
+		return nil, resp.StatusCode, fmt.Errorf("unable to decode response (status %d): %w", resp.StatusCode, err)
+	}
+
+	return respData, resp.StatusCode, nil
+}
+
+// maskVariables returns a copy of variables suitable for logging, with the values of any
+// sensitiveVariables replaced.
+func maskVariables(variables map[string]interface{}) map[string]interface{} {
+	masked := make(map[string]interface{}, len(variables))
+
+	for k, v := range variables {
+		if sensitiveVariables[k] {
+			masked[k] = "***"
+		} else if nested, ok := v.(map[string]interface{}); ok {
+			masked[k] = maskVariables(nested)
+		} else {
+			masked[k] = v
+		}
+	}
+
+	return masked
+}
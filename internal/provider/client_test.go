@@ -0,0 +1,145 @@
+package provider
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+// apqRequest is the body of a GraphQL request sent as an Automatic Persisted Query.
+type apqRequest struct {
+	OperationName string  `json:"operationName"`
+	Query         *string `json:"query"`
+	Extensions    struct {
+		PersistedQuery *struct {
+			Version    int    `json:"version"`
+			Sha256Hash string `json:"sha256Hash"`
+		} `json:"persistedQuery"`
+	} `json:"extensions"`
+}
+
+// apqServer is a fake GrackDB server supporting Automatic Persisted Queries. It rejects hashes it
+// hasn't been sent the document of, and records every request it receives.
+type apqServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	known    map[string]bool
+	requests []apqRequest
+}
+
+func newAPQServer(t *testing.T, response string) *apqServer {
+	s := &apqServer{known: map[string]bool{}}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request apqRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Errorf("unable to decode request: %s", err)
+		}
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.requests = append(s.requests, request)
+
+		if request.Extensions.PersistedQuery == nil {
+			t.Error("request wasn't sent as a persisted query")
+			return
+		}
+		hash := request.Extensions.PersistedQuery.Sha256Hash
+
+		if request.Query != nil {
+			sum := sha256.Sum256([]byte(*request.Query))
+			if hex.EncodeToString(sum[:]) != hash {
+				w.Write([]byte(`{"errors": [{"message": "provided sha does not match query"}]}`))
+				return
+			}
+			s.known[hash] = true
+		}
+		if !s.known[hash] {
+			w.Write([]byte(`{"errors": [{"message": "` + persistedQueryNotFound + `"}]}`))
+			return
+		}
+
+		w.Write([]byte(response))
+	}))
+	t.Cleanup(s.Close)
+
+	return s
+}
+
+func (s *apqServer) client() *apiClient {
+	return &apiClient{
+		httpClient:       s.Client(),
+		apiUrl:           s.URL,
+		credentials:      &credentials{},
+		cache:            newRecordCache(),
+		persistedQueries: true,
+	}
+}
+
+func TestExecutePersistedQuery(t *testing.T) {
+	tests := []struct {
+		name     string
+		op       *operation
+		response string
+	}{
+		{
+			name:     "query",
+			op:       readCurrentUserOperation,
+			response: `{"data": {"currentUser": {"__typename": "User", "id": "u1", "username": "bob", "avatarUrl": null}}}`,
+		},
+		{
+			name:     "mutation",
+			op:       deleteUserOperation,
+			response: `{"data": {"deleteUser": {"id": "u1"}}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newAPQServer(t, tt.response)
+			client := server.client()
+
+			// The server doesn't know the hash yet, so the document is sent after it's rejected.
+			var data interface{}
+			if err := client.execute(context.Background(), tt.op, nil, &data); err != nil {
+				t.Fatalf("execute failed: %s", err)
+			}
+			if len(server.requests) != 2 {
+				t.Fatalf("server received %d requests, want 2", len(server.requests))
+			}
+
+			first, second := server.requests[0], server.requests[1]
+			if first.Query != nil {
+				t.Error("first request included the query document")
+			}
+			if first.Extensions.PersistedQuery == nil || first.Extensions.PersistedQuery.Sha256Hash != tt.op.hash {
+				t.Errorf("first request didn't identify the operation by its hash %s", tt.op.hash)
+			}
+			if second.Query == nil || *second.Query != tt.op.document {
+				t.Error("retried request didn't include the query document")
+			}
+			if second.Extensions.PersistedQuery == nil || second.Extensions.PersistedQuery.Sha256Hash != tt.op.hash {
+				t.Error("retried request didn't include the persisted query extension")
+			}
+			for i, request := range server.requests {
+				if request.OperationName != tt.op.name {
+					t.Errorf("request %d had operationName %q, want %q", i, request.OperationName, tt.op.name)
+				}
+			}
+
+			// Once the server knows the hash, only the hash is sent.
+			server.requests = nil
+			if err := client.execute(context.Background(), tt.op, nil, &data); err != nil {
+				t.Fatalf("execute failed: %s", err)
+			}
+			if len(server.requests) != 1 || server.requests[0].Query != nil {
+				t.Errorf("server received %d requests once it knew the hash, want 1 without the document", len(server.requests))
+			}
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+
+	"github.com/fogo-sh/terraform-provider-grackdb/internal/auth"
+	"golang.org/x/oauth2"
+)
+
+// credentials holds the API token sent with each request, along with how to read it again
+// should the API reject it after being rotated.
+type credentials struct {
+	mu    sync.Mutex
+	token string
+
+	// source describes where the token was read from, for logging.
+	source string
+
+	// load reads the token from its source, nil when the token can't change.
+	load func(ctx context.Context) (string, error)
+}
+
+// newCredentials reads the initial token using load.
+func newCredentials(ctx context.Context, source string, load func(ctx context.Context) (string, error)) (*credentials, error) {
+	token, err := load(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &credentials{token: token, source: source, load: load}, nil
+}
+
+// current returns the token to send with requests, which is empty when unauthenticated.
+func (c *credentials) current() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.token
+}
+
+// reload reads the token from its source again after stale was rejected, reporting whether
+// a different token is now available. Concurrent callers holding the same stale token only
+// trigger a single read.
+func (c *credentials) reload(ctx context.Context, stale string) (bool, error) {
+	if c.load == nil {
+		return false, nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.token != stale {
+		return true, nil
+	}
+
+	token, err := c.load(ctx)
+	if err != nil {
+		return false, err
+	}
+	c.token = token
+
+	return token != stale, nil
+}
+
+// tokenFromFile returns a loader reading the token from path, ignoring surrounding whitespace.
+func tokenFromFile(path string) func(ctx context.Context) (string, error) {
+	return func(ctx context.Context) (string, error) {
+		contents, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("unable to read token file: %w", err)
+		}
+
+		token := strings.TrimSpace(string(contents))
+		if token == "" {
+			return "", fmt.Errorf("token file %s is empty", path)
+		}
+
+		return token, nil
+	}
+}
+
+// credentialsHelperRequest is written to the standard input of a credentials helper.
+type credentialsHelperRequest struct {
+	ApiUrl string `json:"api_url"`
+}
+
+// credentialsHelperResponse is read from the standard output of a credentials helper.
+type credentialsHelperResponse struct {
+	Token string `json:"token"`
+}
+
+// tokenFromHelper returns a loader running the credentials helper command with an additional
+// "get" argument, in the manner of git credential helpers. The helper is sent a JSON
+// credentialsHelperRequest on stdin and must print a JSON credentialsHelperResponse.
+// command is split into arguments on whitespace without any shell quoting, so can't name a
+// helper whose path contains spaces.
+func tokenFromHelper(command string, apiUrl string) func(ctx context.Context) (string, error) {
+	return func(ctx context.Context) (string, error) {
+		args := strings.Fields(command)
+		if len(args) == 0 {
+			return "", fmt.Errorf("credentials helper command is empty")
+		}
+
+		input, err := json.Marshal(credentialsHelperRequest{ApiUrl: apiUrl})
+		if err != nil {
+			return "", err
+		}
+
+		var stdout, stderr bytes.Buffer
+		cmd := exec.CommandContext(ctx, args[0], append(args[1:], "get")...)
+		cmd.Stdin = bytes.NewReader(input)
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+
+		if err := cmd.Run(); err != nil {
+			if msg := strings.TrimSpace(stderr.String()); msg != "" {
+				return "", fmt.Errorf("credentials helper %s failed: %w: %s", args[0], err, msg)
+			}
+			return "", fmt.Errorf("credentials helper %s failed: %w", args[0], err)
+		}
+
+		var output credentialsHelperResponse
+		if err := json.Unmarshal(stdout.Bytes(), &output); err != nil {
+			return "", fmt.Errorf("unable to decode output of credentials helper %s: %w", args[0], err)
+		}
+		if output.Token == "" {
+			return "", fmt.Errorf("credentials helper %s returned no token", args[0])
+		}
+
+		return output.Token, nil
+	}
+}
+
+// tokenFromLogin returns a loader reading the token stored for apiUrl by the login subcommand,
+// refreshing it with httpClient if it has expired.
+func tokenFromLogin(apiUrl string, httpClient *http.Client) func(ctx context.Context) (string, error) {
+	return func(ctx context.Context) (string, error) {
+		return auth.Token(context.WithValue(ctx, oauth2.HTTPClient, httpClient), apiUrl)
+	}
+}
@@ -0,0 +1,250 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestCredentialsReloadOnce(t *testing.T) {
+	var loads atomic.Int32
+	creds := &credentials{token: "stale", load: func(ctx context.Context) (string, error) {
+		loads.Add(1)
+		return "fresh", nil
+	}}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			reloaded, err := creds.reload(context.Background(), "stale")
+			if err != nil || !reloaded {
+				t.Errorf("reload() = %v, %v, want a new token", reloaded, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := loads.Load(); got != 1 {
+		t.Errorf("token read %d times, want 1", got)
+	}
+	if got := creds.current(); got != "fresh" {
+		t.Errorf("current() = %q, want %q", got, "fresh")
+	}
+}
+
+func TestCredentialsReloadUnchanged(t *testing.T) {
+	creds := &credentials{token: "token", load: func(ctx context.Context) (string, error) {
+		return "token", nil
+	}}
+	if reloaded, err := creds.reload(context.Background(), "token"); err != nil || reloaded {
+		t.Errorf("reload() = %v, %v, want no new token", reloaded, err)
+	}
+
+	static := &credentials{token: "token"}
+	if reloaded, err := static.reload(context.Background(), "token"); err != nil || reloaded {
+		t.Errorf("reload() of a static token = %v, %v, want no new token", reloaded, err)
+	}
+}
+
+// writeToken writes token to path, as a rotation of a token_file would.
+func writeToken(t *testing.T, path string, token string) {
+	t.Helper()
+
+	if err := os.WriteFile(path, []byte(token+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestExecuteRetriesAfterTokenFileRotation(t *testing.T) {
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	writeToken(t, tokenFile, "old")
+
+	var mu sync.Mutex
+	var authorizations []string
+	rejectOld := true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		authorization := r.Header.Get("Authorization")
+		authorizations = append(authorizations, authorization)
+		if rejectOld && authorization == "Bearer old" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"errors": [{"message": "invalid token"}]}`))
+			return
+		}
+		w.Write([]byte(`{"data": {"currentUser": {"id": "u1"}}}`))
+	}))
+	defer server.Close()
+
+	creds, err := newCredentials(context.Background(), "token_file", tokenFromFile(tokenFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &apiClient{
+		httpClient:  server.Client(),
+		apiUrl:      server.URL,
+		credentials: creds,
+		cache:       newRecordCache(),
+	}
+	op := &operation{name: "TestCurrentUser", document: `query TestCurrentUser { currentUser { id } }`}
+
+	writeToken(t, tokenFile, "new")
+
+	var data interface{}
+	if err := client.execute(context.Background(), op, nil, &data); err != nil {
+		t.Fatalf("execute failed after the token was rotated: %s", err)
+	}
+	if want := []string{"Bearer old", "Bearer new"}; strings.Join(authorizations, ",") != strings.Join(want, ",") {
+		t.Errorf("GrackDB received Authorization %q, want %q", authorizations, want)
+	}
+
+	// Once rotated, the new token is used without reading the file again.
+	authorizations = nil
+	if err := client.execute(context.Background(), op, nil, &data); err != nil {
+		t.Fatalf("execute failed: %s", err)
+	}
+	if want := []string{"Bearer new"}; strings.Join(authorizations, ",") != strings.Join(want, ",") {
+		t.Errorf("GrackDB received Authorization %q, want %q", authorizations, want)
+	}
+}
+
+func TestExecuteRejectedWithoutRotation(t *testing.T) {
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	writeToken(t, tokenFile, "old")
+
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"errors": [{"message": "invalid token"}]}`))
+	}))
+	defer server.Close()
+
+	creds, err := newCredentials(context.Background(), "token_file", tokenFromFile(tokenFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &apiClient{
+		httpClient:  server.Client(),
+		apiUrl:      server.URL,
+		credentials: creds,
+		cache:       newRecordCache(),
+	}
+	op := &operation{name: "TestCurrentUser", document: `query TestCurrentUser { currentUser { id } }`}
+
+	var data interface{}
+	if err := client.execute(context.Background(), op, nil, &data); err == nil {
+		t.Error("execute succeeded with a rejected token")
+	}
+	if got := requests.Load(); got != 1 {
+		t.Errorf("GrackDB received %d requests, want 1 as the token wasn't rotated", got)
+	}
+}
+
+func TestTokenFromFile(t *testing.T) {
+	dir := t.TempDir()
+
+	tokenFile := filepath.Join(dir, "token")
+	writeToken(t, tokenFile, "  token  ")
+	if token, err := tokenFromFile(tokenFile)(context.Background()); err != nil || token != "token" {
+		t.Errorf("tokenFromFile() = %q, %v, want the trimmed token", token, err)
+	}
+
+	emptyFile := filepath.Join(dir, "empty")
+	writeToken(t, emptyFile, "")
+	if _, err := tokenFromFile(emptyFile)(context.Background()); err == nil || !strings.Contains(err.Error(), "empty") {
+		t.Errorf("tokenFromFile() of an empty file error = %v, want it reported as empty", err)
+	}
+
+	if _, err := tokenFromFile(filepath.Join(dir, "missing"))(context.Background()); err == nil {
+		t.Error("tokenFromFile() of a missing file returned no error")
+	}
+}
+
+// writeHelper writes a credentials helper shell script running script to a temporary directory,
+// returning its path.
+func writeHelper(t *testing.T, script string) string {
+	t.Helper()
+
+	if runtime.GOOS == "windows" {
+		t.Skip("credentials helper tests use shell scripts")
+	}
+
+	path := filepath.Join(t.TempDir(), "helper")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+script+"\n"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestTokenFromHelper(t *testing.T) {
+	// The helper returns its arguments as the token.
+	helper := writeHelper(t, `printf '{"token": "%s"}' "$*"`)
+
+	token, err := tokenFromHelper(helper+" --store keychain", "https://grackdb.example.com/query")(context.Background())
+	if want := "--store keychain get"; err != nil || token != want {
+		t.Errorf("tokenFromHelper() = %q, %v, want %q", token, err, want)
+	}
+}
+
+func TestTokenFromHelperErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		script  string
+		wantErr string
+	}{
+		{name: "failure with stderr", script: `echo "not logged in" >&2; exit 1`, wantErr: "not logged in"},
+		{name: "failure without stderr", script: `exit 3`, wantErr: "exit status 3"},
+		{name: "empty token", script: `echo '{"token": ""}'`, wantErr: "returned no token"},
+		{name: "invalid output", script: `echo token`, wantErr: "unable to decode output"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			helper := writeHelper(t, tt.script)
+
+			_, err := tokenFromHelper(helper, "https://grackdb.example.com/query")(context.Background())
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want one containing %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	if _, err := tokenFromHelper("  ", "https://grackdb.example.com/query")(context.Background()); err == nil {
+		t.Error("empty credentials helper command returned no error")
+	}
+}
+
+func TestTokenFromHelperRequest(t *testing.T) {
+	dir := t.TempDir()
+	requestFile := filepath.Join(dir, "request.json")
+	helper := writeHelper(t, `cat > '`+requestFile+`'; echo '{"token": "token"}'`)
+
+	if _, err := tokenFromHelper(helper, "https://grackdb.example.com/query")(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	contents, err := os.ReadFile(requestFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var request credentialsHelperRequest
+	if err := json.Unmarshal(contents, &request); err != nil {
+		t.Fatalf("helper was sent invalid JSON %q: %s", contents, err)
+	}
+	if request.ApiUrl != "https://grackdb.example.com/query" {
+		t.Errorf("helper was sent api_url %q, want the configured API URL", request.ApiUrl)
+	}
+}
@@ -1,108 +1,127 @@
 package provider
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
-	"io/ioutil"
+	"fmt"
 
-	"github.com/fogo-sh/terraform-provider-grackdb/internal/types"
-	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
-	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	grackdb "github.com/fogo-sh/terraform-provider-grackdb/internal/types"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
 )
 
-func dataSourceCurrentUser() *schema.Resource {
-	return &schema.Resource{
-		Description: "Get details on the current authenticated user.",
+var (
+	_ datasource.DataSource              = &currentUserDataSource{}
+	_ datasource.DataSourceWithConfigure = &currentUserDataSource{}
+)
+
+func NewCurrentUserDataSource() datasource.DataSource {
+	return &currentUserDataSource{}
+}
+
+type currentUserDataSource struct {
+	client *apiClient
+}
+
+type currentUserDataSourceModel struct {
+	ID        types.String `tfsdk:"id"`
+	Username  types.String `tfsdk:"username"`
+	AvatarURL types.String `tfsdk:"avatar_url"`
+}
 
-		ReadContext: dataSourceCurrentUserRead,
+func (d *currentUserDataSource) Metadata(ctx context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_current_user"
+}
+
+func (d *currentUserDataSource) Schema(ctx context.Context, req datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Get details on the current authenticated user.",
 
-		Schema: map[string]*schema.Schema{
-			"id": {
-				Description: "Unique ID for this user.",
-				Type:        schema.TypeString,
-				Computed:    true,
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				MarkdownDescription: "Unique ID for this user.",
+				Computed:            true,
 			},
-			"username": {
-				Description: "Unique username for this user.",
-				Type:        schema.TypeString,
-				Computed:    true,
+			"username": schema.StringAttribute{
+				MarkdownDescription: "Unique username for this user.",
+				Computed:            true,
 			},
-			"avatar_url": {
-				Description: "URL for this user's avatar.",
-				Type:        schema.TypeString,
-				Computed:    true,
+			"avatar_url": schema.StringAttribute{
+				MarkdownDescription: "URL for this user's avatar.",
+				Computed:            true,
 			},
 		},
 	}
 }
 
-type currentUserResp struct {
-	Data struct {
-		CurrentUser *types.User `json:"currentUser"`
-	} `json:"data"`
-}
-
-func dataSourceCurrentUserRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
-	client := meta.(*apiClient)
-
-	reqBody, err := json.Marshal(map[string]interface{}{
-		"operationName": nil,
-		"query": `
-			{
-				currentUser {
-					id
-					username
-					avatarUrl
-				}
-			}
-		`,
-		"variables": map[string]interface{}{},
-	})
-	if err != nil {
-		return diag.FromErr(err)
+func (d *currentUserDataSource) Configure(ctx context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
 	}
 
-	resp, err := client.httpClient.Post(
-		client.apiUrl,
-		"application/json",
-		bytes.NewBuffer(reqBody),
-	)
-	if err != nil {
-		return diag.FromErr(err)
+	client, ok := req.ProviderData.(*apiClient)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Data Source Configure Type",
+			fmt.Sprintf("Expected *apiClient, got: %T.", req.ProviderData),
+		)
+		return
 	}
-	defer resp.Body.Close()
 
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return diag.FromErr(err)
+	d.client = client
+}
+
+var readCurrentUserOperation = newOperation("TerraformReadCurrentUser", `
+	query TerraformReadCurrentUser {
+		currentUser {
+			...UserFields
+		}
 	}
+`+grackdb.UserFragment)
+
+type currentUserResp struct {
+	CurrentUser *grackdb.User `json:"currentUser"`
+}
 
+// fetchCurrentUser returns the user the client is authenticated as, or nil if the token isn't
+// recognised.
+func (c *apiClient) fetchCurrentUser(ctx context.Context) (*grackdb.User, error) {
 	respData := new(currentUserResp)
-	err = json.Unmarshal(body, &respData)
+	err := c.execute(ctx, readCurrentUserOperation, nil, respData)
 	if err != nil {
-		return diag.FromErr(err)
+		return nil, err
 	}
 
-	if respData.Data.CurrentUser == nil {
-		return diag.Diagnostics{
-			diag.Diagnostic{
-				Summary: "Failed to retrieve current user. Please ensure you've provided a valid api token.",
-			},
-		}
-	}
+	return respData.CurrentUser, nil
+}
 
-	d.SetId(respData.Data.CurrentUser.ID)
+func (d *currentUserDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	ctx, span := d.client.startSpan(ctx, "grackdb_current_user.Read")
+	defer func() { d.client.endSpan(ctx, span, resp.Diagnostics) }()
 
-	if err = d.Set("id", respData.Data.CurrentUser.ID); err != nil {
-		return diag.FromErr(err)
+	currentUser := d.client.currentUser
+	if currentUser == nil {
+		var err error
+		currentUser, err = d.client.fetchCurrentUser(ctx)
+		if err != nil {
+			resp.Diagnostics.AddError("Failed to retrieve current user", err.Error())
+			return
+		}
 	}
-	if err = d.Set("username", respData.Data.CurrentUser.Username); err != nil {
-		return diag.FromErr(err)
+
+	if currentUser == nil {
+		resp.Diagnostics.AddError(
+			"Failed to retrieve current user",
+			"Please ensure you've provided a valid api token.",
+		)
+		return
 	}
-	if err = d.Set("avatar_url", respData.Data.CurrentUser.AvatarURL); err != nil {
-		return diag.FromErr(err)
+
+	state := currentUserDataSourceModel{
+		ID:        types.StringValue(currentUser.ID),
+		Username:  types.StringValue(currentUser.Username),
+		AvatarURL: types.StringPointerValue(currentUser.AvatarURL),
 	}
 
-	return diag.Diagnostics{}
+	resp.Diagnostics.Append(resp.State.Set(ctx, &state)...)
 }
@@ -0,0 +1,76 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Values of on_destroy, controlling what destroying a resource does to its GrackDB record.
+const (
+	onDestroyDelete  = "delete"
+	onDestroyArchive = "archive"
+	onDestroyAbandon = "abandon"
+)
+
+var onDestroyValues = []string{onDestroyDelete, onDestroyArchive, onDestroyAbandon}
+
+// onDestroyAttribute returns the on_destroy attribute of a resource managing records described by noun.
+func onDestroyAttribute(noun string) schema.StringAttribute {
+	return schema.StringAttribute{
+		MarkdownDescription: fmt.Sprintf("What destroying this resource does to the %s in GrackDB: `delete` it, `archive` it, keeping its history, or `abandon` it, only removing it from Terraform state. ", noun) +
+			"Defaults to the provider's `on_destroy`. `deletion_protection` only prevents `delete`.",
+		Optional: true,
+		Validators: []validator.String{
+			onDestroyValidator{},
+		},
+	}
+}
+
+// onDestroy resolves the on_destroy setting of a resource, falling back to the provider's.
+func (c *apiClient) onDestroy(value types.String) string {
+	if !value.IsNull() && !value.IsUnknown() {
+		return value.ValueString()
+	}
+
+	return c.defaultOnDestroy
+}
+
+func validOnDestroy(value string) bool {
+	for _, v := range onDestroyValues {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+type onDestroyValidator struct{}
+
+var _ validator.String = onDestroyValidator{}
+
+func (v onDestroyValidator) Description(ctx context.Context) string {
+	return "value must be one of " + strings.Join(onDestroyValues, ", ")
+}
+
+func (v onDestroyValidator) MarkdownDescription(ctx context.Context) string {
+	return v.Description(ctx)
+}
+
+func (v onDestroyValidator) ValidateString(ctx context.Context, req validator.StringRequest, resp *validator.StringResponse) {
+	if req.ConfigValue.IsNull() || req.ConfigValue.IsUnknown() {
+		return
+	}
+
+	if !validOnDestroy(req.ConfigValue.ValueString()) {
+		resp.Diagnostics.AddAttributeError(
+			req.Path,
+			"Invalid on_destroy",
+			fmt.Sprintf("on_destroy must be one of %s, got %q.", strings.Join(onDestroyValues, ", "), req.ConfigValue.ValueString()),
+		)
+	}
+}
@@ -0,0 +1,69 @@
+package provider
+
+import (
+	"context"
+
+	"github.com/fogo-sh/terraform-provider-grackdb/internal/discord"
+	"github.com/hashicorp/terraform-plugin-framework/function"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+var _ function.Function = &discordAvatarURLFunction{}
+
+func NewDiscordAvatarURLFunction() function.Function {
+	return &discordAvatarURLFunction{}
+}
+
+type discordAvatarURLFunction struct{}
+
+func (f *discordAvatarURLFunction) Metadata(ctx context.Context, req function.MetadataRequest, resp *function.MetadataResponse) {
+	resp.Name = "discord_avatar_url"
+}
+
+func (f *discordAvatarURLFunction) Definition(ctx context.Context, req function.DefinitionRequest, resp *function.DefinitionResponse) {
+	resp.Definition = function.Definition{
+		Summary:             "Build the CDN URL of a Discord user's avatar.",
+		MarkdownDescription: "Returns the Discord CDN URL for a user's avatar. When `avatar_hash` is null the URL of the default avatar Discord assigns to the user is returned instead, which for accounts that haven't migrated to unique usernames depends on their `discriminator`.",
+
+		Parameters: []function.Parameter{
+			function.StringParameter{
+				Name:                "discord_id",
+				MarkdownDescription: "Discord snowflake of the user.",
+			},
+			function.StringParameter{
+				Name:                "avatar_hash",
+				MarkdownDescription: "Avatar hash of the user, or null if they haven't set an avatar.",
+				AllowNullValue:      true,
+			},
+			function.StringParameter{
+				Name:                "discriminator",
+				MarkdownDescription: "Discriminator of the user, or null if they've migrated to unique usernames.",
+				AllowNullValue:      true,
+			},
+		},
+		Return: function.StringReturn{},
+	}
+}
+
+func (f *discordAvatarURLFunction) Run(ctx context.Context, req function.RunRequest, resp *function.RunResponse) {
+	var discordID string
+	var avatarHash, discriminator types.String
+
+	resp.Error = function.ConcatFuncErrors(resp.Error, req.Arguments.Get(ctx, &discordID, &avatarHash, &discriminator))
+	if resp.Error != nil {
+		return
+	}
+
+	if _, err := discord.ParseSnowflake(discordID); err != nil {
+		resp.Error = function.NewArgumentFuncError(0, err.Error())
+		return
+	}
+
+	avatarURL, err := discord.AvatarURL(discordID, avatarHash.ValueString(), discriminator.ValueString())
+	if err != nil {
+		resp.Error = function.NewArgumentFuncError(2, err.Error())
+		return
+	}
+
+	resp.Error = function.ConcatFuncErrors(resp.Error, resp.Result.Set(ctx, avatarURL))
+}
@@ -0,0 +1,53 @@
+package provider
+
+import (
+	"context"
+	"time"
+
+	"github.com/fogo-sh/terraform-provider-grackdb/internal/discord"
+	"github.com/hashicorp/terraform-plugin-framework/function"
+)
+
+var _ function.Function = &snowflakeTimestampFunction{}
+
+func NewSnowflakeTimestampFunction() function.Function {
+	return &snowflakeTimestampFunction{}
+}
+
+type snowflakeTimestampFunction struct{}
+
+func (f *snowflakeTimestampFunction) Metadata(ctx context.Context, req function.MetadataRequest, resp *function.MetadataResponse) {
+	resp.Name = "snowflake_timestamp"
+}
+
+func (f *snowflakeTimestampFunction) Definition(ctx context.Context, req function.DefinitionRequest, resp *function.DefinitionResponse) {
+	resp.Definition = function.Definition{
+		Summary:             "Get the creation time of a Discord snowflake.",
+		MarkdownDescription: "Returns the time the given Discord snowflake was created, as an RFC 3339 timestamp in UTC.",
+
+		Parameters: []function.Parameter{
+			function.StringParameter{
+				Name:                "snowflake",
+				MarkdownDescription: "Discord snowflake to get the creation time of.",
+			},
+		},
+		Return: function.StringReturn{},
+	}
+}
+
+func (f *snowflakeTimestampFunction) Run(ctx context.Context, req function.RunRequest, resp *function.RunResponse) {
+	var snowflake string
+
+	resp.Error = function.ConcatFuncErrors(resp.Error, req.Arguments.Get(ctx, &snowflake))
+	if resp.Error != nil {
+		return
+	}
+
+	timestamp, err := discord.SnowflakeTimestamp(snowflake)
+	if err != nil {
+		resp.Error = function.NewArgumentFuncError(0, err.Error())
+		return
+	}
+
+	resp.Error = function.ConcatFuncErrors(resp.Error, resp.Result.Set(ctx, timestamp.Format(time.RFC3339Nano)))
+}
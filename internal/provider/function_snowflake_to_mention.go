@@ -0,0 +1,52 @@
+package provider
+
+import (
+	"context"
+
+	"github.com/fogo-sh/terraform-provider-grackdb/internal/discord"
+	"github.com/hashicorp/terraform-plugin-framework/function"
+)
+
+var _ function.Function = &snowflakeToMentionFunction{}
+
+func NewSnowflakeToMentionFunction() function.Function {
+	return &snowflakeToMentionFunction{}
+}
+
+type snowflakeToMentionFunction struct{}
+
+func (f *snowflakeToMentionFunction) Metadata(ctx context.Context, req function.MetadataRequest, resp *function.MetadataResponse) {
+	resp.Name = "snowflake_to_mention"
+}
+
+func (f *snowflakeToMentionFunction) Definition(ctx context.Context, req function.DefinitionRequest, resp *function.DefinitionResponse) {
+	resp.Definition = function.Definition{
+		Summary:             "Build a Discord user mention from a snowflake.",
+		MarkdownDescription: "Returns the message syntax (`<@snowflake>`) that mentions the Discord user with the given snowflake.",
+
+		Parameters: []function.Parameter{
+			function.StringParameter{
+				Name:                "snowflake",
+				MarkdownDescription: "Discord snowflake of the user to mention.",
+			},
+		},
+		Return: function.StringReturn{},
+	}
+}
+
+func (f *snowflakeToMentionFunction) Run(ctx context.Context, req function.RunRequest, resp *function.RunResponse) {
+	var snowflake string
+
+	resp.Error = function.ConcatFuncErrors(resp.Error, req.Arguments.Get(ctx, &snowflake))
+	if resp.Error != nil {
+		return
+	}
+
+	mention, err := discord.UserMention(snowflake)
+	if err != nil {
+		resp.Error = function.NewArgumentFuncError(0, err.Error())
+		return
+	}
+
+	resp.Error = function.ConcatFuncErrors(resp.Error, resp.Result.Set(ctx, mention))
+}
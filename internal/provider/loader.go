@@ -0,0 +1,145 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+const (
+	// batchWindow is how long a loader waits for further lookups before sending a batch.
+	batchWindow = 10 * time.Millisecond
+
+	// maxBatchSize caps the number of IDs requested at once, larger batches are sent immediately.
+	maxBatchSize = 100
+)
+
+// batchLoader coalesces concurrent lookups of records by ID, such as the reads Terraform issues
+// in parallel while refreshing state, into a single API request.
+type batchLoader[T any] struct {
+	cache    *recordCache
+	typename string
+	fetch    func(ctx context.Context, ids []string) (map[string]T, error)
+
+	mu      sync.Mutex
+	pending *loaderBatch[T]
+}
+
+type loaderBatch[T any] struct {
+	// ctx is shared by every caller waiting on the batch, and is cancelled once they've all
+	// given up on it.
+	ctx     context.Context
+	cancel  context.CancelFunc
+	waiters int
+
+	ids     []string
+	done    chan struct{}
+	results map[string]T
+	err     error
+}
+
+// newBatchLoader creates a loader for records of the GraphQL type typename, serving them from
+// cache when they've already been seen during this run.
+func newBatchLoader[T any](cache *recordCache, typename string, fetch func(ctx context.Context, ids []string) (map[string]T, error)) *batchLoader[T] {
+	return &batchLoader[T]{cache: cache, typename: typename, fetch: fetch}
+}
+
+// load returns the record with the given ID, reporting whether it was found.
+func (l *batchLoader[T]) load(ctx context.Context, id string) (T, bool, error) {
+	if cached, ok := l.cache.get(l.typename, id); ok {
+		var record T
+		if err := json.Unmarshal(cached, &record); err == nil {
+			tflog.Trace(ctx, "Serving record from cache", map[string]interface{}{
+				"graphql_type": l.typename,
+				"id":           id,
+			})
+			return record, true, nil
+		}
+	}
+
+	l.mu.Lock()
+	batch := l.pending
+	if batch == nil {
+		// The batch keeps the values of the context of the caller that happened to start it,
+		// but mustn't be cancelled along with it while other callers are still waiting.
+		batchCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		batch = &loaderBatch[T]{ctx: batchCtx, cancel: cancel, done: make(chan struct{})}
+		l.pending = batch
+		time.AfterFunc(batchWindow, func() { l.dispatch(batch) })
+	}
+	batch.ids = append(batch.ids, id)
+	batch.waiters++
+	if len(batch.ids) >= maxBatchSize {
+		l.pending = nil
+		go l.send(batch)
+	}
+	l.mu.Unlock()
+
+	var zero T
+	select {
+	case <-ctx.Done():
+		l.leave(batch)
+		return zero, false, ctx.Err()
+	case <-batch.done:
+	}
+
+	if batch.err != nil {
+		return zero, false, batch.err
+	}
+
+	result, ok := batch.results[id]
+	return result, ok, nil
+}
+
+// leave stops waiting on batch, cancelling it if no other callers are waiting on it.
+func (l *batchLoader[T]) leave(batch *loaderBatch[T]) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	batch.waiters--
+	if batch.waiters > 0 {
+		return
+	}
+
+	if l.pending == batch {
+		l.pending = nil
+	}
+	batch.cancel()
+}
+
+// dispatch sends batch if it hasn't already been sent or abandoned.
+func (l *batchLoader[T]) dispatch(batch *loaderBatch[T]) {
+	l.mu.Lock()
+	if l.pending != batch {
+		l.mu.Unlock()
+		return
+	}
+	l.pending = nil
+	l.mu.Unlock()
+
+	l.send(batch)
+}
+
+// send fetches the records in batch, which must no longer be pending.
+func (l *batchLoader[T]) send(batch *loaderBatch[T]) {
+	batch.results, batch.err = l.fetch(batch.ctx, uniqueStrings(batch.ids))
+	batch.cancel()
+	close(batch.done)
+}
+
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	unique := make([]string, 0, len(values))
+
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			unique = append(unique, v)
+		}
+	}
+
+	return unique
+}
@@ -0,0 +1,250 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingFetch is a loader fetch function returning the ID of each record as its value,
+// except for those in missing, and recording the IDs of each batch it receives.
+type recordingFetch struct {
+	mu      sync.Mutex
+	batches [][]string
+	missing map[string]bool
+	err     error
+}
+
+func (f *recordingFetch) fetch(ctx context.Context, ids []string) (map[string]string, error) {
+	f.mu.Lock()
+	f.batches = append(f.batches, ids)
+	f.mu.Unlock()
+
+	if f.err != nil {
+		return nil, f.err
+	}
+
+	results := map[string]string{}
+	for _, id := range ids {
+		if !f.missing[id] {
+			results[id] = "record " + id
+		}
+	}
+	return results, nil
+}
+
+type loadResult struct {
+	id     string
+	record string
+	found  bool
+	err    error
+}
+
+// loadConcurrently loads every ID in ids at once, returning the results in the same order.
+func loadConcurrently(loader *batchLoader[string], ids []string) []loadResult {
+	results := make([]loadResult, len(ids))
+
+	var wg sync.WaitGroup
+	for i, id := range ids {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			record, found, err := loader.load(context.Background(), id)
+			results[i] = loadResult{id: id, record: record, found: found, err: err}
+		}()
+	}
+	wg.Wait()
+
+	return results
+}
+
+func TestBatchLoaderCoalesces(t *testing.T) {
+	fetch := &recordingFetch{}
+	loader := newBatchLoader(newRecordCache(), "User", fetch.fetch)
+
+	var ids []string
+	for i := 0; i < 10; i++ {
+		ids = append(ids, fmt.Sprint(i))
+	}
+
+	for _, result := range loadConcurrently(loader, ids) {
+		if result.err != nil || !result.found || result.record != "record "+result.id {
+			t.Errorf("load(%q) = %q, %v, %v", result.id, result.record, result.found, result.err)
+		}
+	}
+
+	if len(fetch.batches) != 1 {
+		t.Fatalf("fetched %d batches, want 1", len(fetch.batches))
+	}
+	got := append([]string(nil), fetch.batches[0]...)
+	sort.Strings(got)
+	sort.Strings(ids)
+	if fmt.Sprint(got) != fmt.Sprint(ids) {
+		t.Errorf("fetched %v, want %v", got, ids)
+	}
+}
+
+func TestBatchLoaderSplitsLargeBatches(t *testing.T) {
+	fetch := &recordingFetch{}
+	loader := newBatchLoader(newRecordCache(), "User", fetch.fetch)
+
+	var ids []string
+	for i := 0; i < maxBatchSize*2+1; i++ {
+		ids = append(ids, fmt.Sprint(i))
+	}
+
+	for _, result := range loadConcurrently(loader, ids) {
+		if result.err != nil || !result.found {
+			t.Errorf("load(%q) = %v, %v", result.id, result.found, result.err)
+		}
+	}
+
+	if len(fetch.batches) < 3 {
+		t.Errorf("fetched %d batches, want at least 3", len(fetch.batches))
+	}
+	fetched := 0
+	for _, batch := range fetch.batches {
+		if len(batch) > maxBatchSize {
+			t.Errorf("fetched a batch of %d IDs, want at most %d", len(batch), maxBatchSize)
+		}
+		fetched += len(batch)
+	}
+	if fetched != len(ids) {
+		t.Errorf("fetched %d IDs, want %d", fetched, len(ids))
+	}
+}
+
+func TestBatchLoaderDeduplicates(t *testing.T) {
+	fetch := &recordingFetch{}
+	loader := newBatchLoader(newRecordCache(), "User", fetch.fetch)
+
+	for _, result := range loadConcurrently(loader, []string{"1", "1", "1", "2"}) {
+		if result.err != nil || !result.found || result.record != "record "+result.id {
+			t.Errorf("load(%q) = %q, %v, %v", result.id, result.record, result.found, result.err)
+		}
+	}
+
+	if len(fetch.batches) != 1 {
+		t.Fatalf("fetched %d batches, want 1", len(fetch.batches))
+	}
+	if len(fetch.batches[0]) != 2 {
+		t.Errorf("fetched %v, want each ID once", fetch.batches[0])
+	}
+}
+
+func TestBatchLoaderMissing(t *testing.T) {
+	fetch := &recordingFetch{missing: map[string]bool{"2": true}}
+	loader := newBatchLoader(newRecordCache(), "User", fetch.fetch)
+
+	for _, result := range loadConcurrently(loader, []string{"1", "2"}) {
+		if result.err != nil {
+			t.Errorf("load(%q) returned error %s", result.id, result.err)
+		}
+		if wantFound := result.id != "2"; result.found != wantFound {
+			t.Errorf("load(%q) found = %v, want %v", result.id, result.found, wantFound)
+		}
+	}
+}
+
+func TestBatchLoaderError(t *testing.T) {
+	fetchErr := errors.New("GrackDB is down")
+	fetch := &recordingFetch{err: fetchErr}
+	loader := newBatchLoader(newRecordCache(), "User", fetch.fetch)
+
+	for _, result := range loadConcurrently(loader, []string{"1", "2", "3"}) {
+		if !errors.Is(result.err, fetchErr) {
+			t.Errorf("load(%q) error = %v, want %v", result.id, result.err, fetchErr)
+		}
+	}
+}
+
+// blockingFetch is a loader fetch function that blocks until released, reporting the
+// context each batch was fetched with.
+type blockingFetch struct {
+	started  chan context.Context
+	released chan struct{}
+}
+
+func newBlockingFetch() *blockingFetch {
+	return &blockingFetch{started: make(chan context.Context, 1), released: make(chan struct{})}
+}
+
+func (f *blockingFetch) fetch(ctx context.Context, ids []string) (map[string]string, error) {
+	f.started <- ctx
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-f.released:
+	}
+
+	results := map[string]string{}
+	for _, id := range ids {
+		results[id] = "record " + id
+	}
+	return results, nil
+}
+
+func TestBatchLoaderCallerCancelled(t *testing.T) {
+	fetch := newBlockingFetch()
+	loader := newBatchLoader(newRecordCache(), "User", fetch.fetch)
+
+	cancelledCtx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cancelledErr := make(chan error, 1)
+	go func() {
+		_, _, err := loader.load(cancelledCtx, "1")
+		cancelledErr <- err
+	}()
+
+	waiting := make(chan loadResult, 1)
+	go func() {
+		record, found, err := loader.load(context.Background(), "2")
+		waiting <- loadResult{record: record, found: found, err: err}
+	}()
+
+	fetchCtx := <-fetch.started
+	cancel()
+	if err := <-cancelledErr; !errors.Is(err, context.Canceled) {
+		t.Errorf("cancelled load error = %v, want %v", err, context.Canceled)
+	}
+	if err := fetchCtx.Err(); err != nil {
+		t.Errorf("batch context error = %v while a caller is still waiting", err)
+	}
+
+	close(fetch.released)
+	if result := <-waiting; result.err != nil || !result.found || result.record != "record 2" {
+		t.Errorf("load(%q) = %q, %v, %v", "2", result.record, result.found, result.err)
+	}
+}
+
+func TestBatchLoaderAllCallersCancelled(t *testing.T) {
+	fetch := newBlockingFetch()
+	loader := newBatchLoader(newRecordCache(), "User", fetch.fetch)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	loadErr := make(chan error, 1)
+	go func() {
+		_, _, err := loader.load(ctx, "1")
+		loadErr <- err
+	}()
+
+	fetchCtx := <-fetch.started
+	cancel()
+	if err := <-loadErr; !errors.Is(err, context.Canceled) {
+		t.Errorf("load error = %v, want %v", err, context.Canceled)
+	}
+
+	select {
+	case <-fetchCtx.Done():
+	case <-time.After(time.Second):
+		t.Error("batch context wasn't cancelled after every caller gave up on it")
+	}
+}
@@ -0,0 +1,84 @@
+package provider
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+	"strings"
+)
+
+// operation is a named GraphQL document sent by the provider.
+type operation struct {
+	name     string
+	document string
+
+	// hash is the hex encoded SHA-256 of document, identifying it as a persisted query.
+	hash string
+}
+
+// operations holds every operation the provider can send, for generating the persisted query manifest.
+var operations []*operation
+
+// newOperation registers a GraphQL document, which must define a single operation named name
+// along with any fragments it uses.
+func newOperation(name string, document string) *operation {
+	sum := sha256.Sum256([]byte(document))
+
+	op := &operation{
+		name:     name,
+		document: document,
+		hash:     hex.EncodeToString(sum[:]),
+	}
+	operations = append(operations, op)
+
+	return op
+}
+
+func (o *operation) isMutation() bool {
+	return strings.HasPrefix(strings.TrimSpace(o.document), "mutation")
+}
+
+func (o *operation) operationType() string {
+	if o.isMutation() {
+		return "mutation"
+	}
+	return "query"
+}
+
+type persistedQueryManifest struct {
+	Format     string                    `json:"format"`
+	Version    int                       `json:"version"`
+	Operations []persistedQueryOperation `json:"operations"`
+}
+
+type persistedQueryOperation struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Type string `json:"type"`
+	Body string `json:"body"`
+}
+
+// PersistedQueryManifest returns a manifest of every operation the provider sends, in the
+// Apollo persisted query manifest format, for allow-listing them on the GrackDB server.
+func PersistedQueryManifest() ([]byte, error) {
+	manifest := persistedQueryManifest{
+		Format:     "apollo-persisted-query-manifest",
+		Version:    1,
+		Operations: make([]persistedQueryOperation, 0, len(operations)),
+	}
+
+	for _, op := range operations {
+		manifest.Operations = append(manifest.Operations, persistedQueryOperation{
+			ID:   op.hash,
+			Name: op.name,
+			Type: op.operationType(),
+			Body: op.document,
+		})
+	}
+	sort.Slice(manifest.Operations, func(i, j int) bool {
+		return manifest.Operations[i].Name < manifest.Operations[j].Name
+	})
+
+	return json.MarshalIndent(manifest, "", "  ")
+}
@@ -0,0 +1,34 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+)
+
+// modifyPlan applies the plan checks shared by every resource, rejecting any change to
+// resourceType when the provider is read-only.
+func (c *apiClient) modifyPlan(ctx context.Context, resourceType string, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	// The provider isn't configured yet when its configuration depends on unknown values.
+	if c == nil || !c.readOnly {
+		return
+	}
+
+	var action string
+	switch {
+	case req.Plan.Raw.IsNull():
+		action = "destroyed"
+	case req.State.Raw.IsNull():
+		action = "created"
+	case !req.Plan.Raw.Equal(req.State.Raw):
+		action = "updated"
+	default:
+		return
+	}
+
+	resp.Diagnostics.AddError(
+		"Provider is read-only",
+		fmt.Sprintf("This %s would be %s, but the provider is configured with read_only = true so GrackDB can't be modified.", resourceType, action),
+	)
+}
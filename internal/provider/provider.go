@@ -2,62 +2,237 @@ package provider
 
 import (
 	"context"
+	"fmt"
+	"math"
 	"net/http"
+	"strings"
+	"time"
 
-	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
-	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	grackdb "github.com/fogo-sh/terraform-provider-grackdb/internal/types"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/function"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/provider"
+	"github.com/hashicorp/terraform-plugin-framework/provider/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/net/http/httpguts"
+	"golang.org/x/time/rate"
 )
 
-func init() {
-	// Set descriptions to support markdown syntax, this will be used in document generation
-	// and the language server.
-	schema.DescriptionKind = schema.StringMarkdown
-
-	// Customize the content of descriptions when output. For example you can add defaults on
-	// to the exported descriptions if present.
-	// schema.SchemaDescriptionBuilder = func(s *schema.Schema) string {
-	// 	desc := s.Description
-	// 	if s.Default != nil {
-	// 		desc += fmt.Sprintf(" Defaults to `%v`.", s.Default)
-	// 	}
-	// 	return strings.TrimSpace(desc)
-	// }
+// DefaultApiUrl is the GraphQL endpoint used when api_url isn't configured.
+const DefaultApiUrl = "https://grackdb.fogo.sh/query"
+
+const (
+	defaultRequestTimeout = "60s"
+
+	// defaultResourceTimeout bounds each resource operation when no timeouts block overrides it.
+	defaultResourceTimeout = 20 * time.Minute
+)
+
+var (
+	_ provider.Provider              = &grackdbProvider{}
+	_ provider.ProviderWithFunctions = &grackdbProvider{}
+)
+
+type grackdbProvider struct {
+	version string
 }
 
-func New(version string) func() *schema.Provider {
-	return func() *schema.Provider {
-		p := &schema.Provider{
-			Schema: map[string]*schema.Schema{
-				"api_url": {
-					Type:     schema.TypeString,
-					Optional: true,
-					Default:  "https://grackdb.fogo.sh/query",
-				},
-				"token": {
-					Type:        schema.TypeString,
-					Optional:    true,
-					Sensitive:   true,
-					DefaultFunc: schema.EnvDefaultFunc("GRACKDB_TOKEN", nil),
-				},
-			},
-			DataSourcesMap: map[string]*schema.Resource{
-				"grackdb_current_user": dataSourceCurrentUser(),
-			},
-			ResourcesMap: map[string]*schema.Resource{
-				"grackdb_user":            resourceUser(),
-				"grackdb_discord_account": resourceDiscordAccount(),
-			},
+type grackdbProviderModel struct {
+	Profile                  types.String  `tfsdk:"profile"`
+	ApiUrl                   types.String  `tfsdk:"api_url"`
+	Token                    types.String  `tfsdk:"token"`
+	TokenFile                types.String  `tfsdk:"token_file"`
+	CredentialsHelper        types.String  `tfsdk:"credentials_helper"`
+	OtlpEndpoint             types.String  `tfsdk:"otlp_endpoint"`
+	RequestTimeout           types.String  `tfsdk:"request_timeout"`
+	MaxRequestsPerSecond     types.Float64 `tfsdk:"max_requests_per_second"`
+	Burst                    types.Int64   `tfsdk:"burst"`
+	PersistedQueries         types.Bool    `tfsdk:"persisted_queries"`
+	CompressRequests         types.Bool    `tfsdk:"compress_requests"`
+	CaCertPem                types.String  `tfsdk:"ca_cert_pem"`
+	CaCertFile               types.String  `tfsdk:"ca_cert_file"`
+	ClientCert               types.String  `tfsdk:"client_cert"`
+	ClientKey                types.String  `tfsdk:"client_key"`
+	InsecureSkipVerify       types.Bool    `tfsdk:"insecure_skip_verify"`
+	ProxyUrl                 types.String  `tfsdk:"proxy_url"`
+	Headers                  types.Map     `tfsdk:"headers"`
+	AllowAuthorizationHeader types.Bool    `tfsdk:"allow_authorization_header"`
+	ValidateCredentials      types.Bool    `tfsdk:"validate_credentials"`
+	ReadOnly                 types.Bool    `tfsdk:"read_only"`
+	OnDestroy                types.String  `tfsdk:"on_destroy"`
+}
+
+func New(version string) func() provider.Provider {
+	return func() provider.Provider {
+		return &grackdbProvider{
+			version: version,
 		}
+	}
+}
 
-		p.ConfigureContextFunc = configure(version, p)
+func (p *grackdbProvider) Metadata(ctx context.Context, req provider.MetadataRequest, resp *provider.MetadataResponse) {
+	resp.TypeName = "grackdb"
+	resp.Version = p.version
+}
+
+func (p *grackdbProvider) Schema(ctx context.Context, req provider.SchemaRequest, resp *provider.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Every attribute can also be set with a `GRACKDB_*` environment variable named after it, such as `GRACKDB_API_URL`, or from a profile in the config file. " +
+			"Values set in configuration take precedence over environment variables, which take precedence over the profile. " +
+			"`token`, `token_file` and `credentials_helper` are taken as a group from whichever of these sets any of them.",
 
-		return p
+		Attributes: map[string]schema.Attribute{
+			"profile": schema.StringAttribute{
+				MarkdownDescription: "Name of the profile to read defaults for the other attributes from, defined as a `[profiles.<name>]` table in `grackdb/config.toml` under the user's config directory (such as `~/.config` on Linux). " +
+					"The `default` profile is used when unset, if it exists. Can also be set with the `GRACKDB_PROFILE` environment variable, and the config file location with `GRACKDB_CONFIG_FILE`.",
+				Optional: true,
+			},
+			"api_url": schema.StringAttribute{
+				MarkdownDescription: "URL of the GrackDB GraphQL endpoint. Defaults to `" + DefaultApiUrl + "`. " +
+					"A local server listening on a unix socket can be used with `unix:///path/to/grackdb.sock`, which sends requests to `/query`, or `http+unix://%2Fpath%2Fto%2Fgrackdb.sock/query` with the socket path percent-encoded. Can also be set with the `GRACKDB_API_URL` environment variable.",
+				Optional: true,
+			},
+			"token": schema.StringAttribute{
+				MarkdownDescription: "API token used to authenticate with GrackDB. Can also be set with the `GRACKDB_TOKEN` environment variable. Takes precedence over `token_file`, `credentials_helper` and tokens stored by `terraform-provider-grackdb login`.",
+				Optional:            true,
+				Sensitive:           true,
+			},
+			"token_file": schema.StringAttribute{
+				MarkdownDescription: "Path to a file containing the API token, used when `token` isn't set. Conflicts with `credentials_helper`. The file is read again if GrackDB rejects the token, so it can be rotated while Terraform runs. Can also be set with the `GRACKDB_TOKEN_FILE` environment variable.",
+				Optional:            true,
+			},
+			"credentials_helper": schema.StringAttribute{
+				MarkdownDescription: "Command run with an additional `get` argument to obtain the API token, used when `token` isn't set. Conflicts with `token_file`. " +
+					"The command is split into arguments on whitespace, without shell quoting, so a helper whose path contains spaces must be run through a wrapper script. " +
+					"The command is sent `{\"api_url\": \"...\"}` on stdin and must print `{\"token\": \"...\"}` to stdout. It's run again if GrackDB rejects the token. Can also be set with the `GRACKDB_CREDENTIALS_HELPER` environment variable.",
+				Optional: true,
+			},
+			"request_timeout": schema.StringAttribute{
+				MarkdownDescription: "Maximum time to wait for a single API request to complete, as a positive Go duration string such as `30s`. Defaults to `" + defaultRequestTimeout + "`. Can also be set with the `GRACKDB_REQUEST_TIMEOUT` environment variable.",
+				Optional:            true,
+			},
+			"max_requests_per_second": schema.Float64Attribute{
+				MarkdownDescription: "Maximum number of API requests per second this provider will send, shared across all resources and data sources. Unlimited when unset. Can also be set with the `GRACKDB_MAX_REQUESTS_PER_SECOND` environment variable.",
+				Optional:            true,
+			},
+			"burst": schema.Int64Attribute{
+				MarkdownDescription: "Number of API requests that may be sent at once before `max_requests_per_second` is enforced. Can only be set along with `max_requests_per_second`, and defaults to it rounded up. Can also be set with the `GRACKDB_BURST` environment variable.",
+				Optional:            true,
+			},
+			"persisted_queries": schema.BoolAttribute{
+				MarkdownDescription: "Send operations as Automatic Persisted Queries, identified by the SHA-256 hash of their document, falling back to the full document when the server doesn't recognise the hash. Defaults to `false`. Can also be set with the `GRACKDB_PERSISTED_QUERIES` environment variable.",
+				Optional:            true,
+			},
+			"compress_requests": schema.BoolAttribute{
+				MarkdownDescription: "Gzip request bodies larger than 1KiB. Responses are always requested gzipped. Defaults to `false`. Can also be set with the `GRACKDB_COMPRESS_REQUESTS` environment variable.",
+				Optional:            true,
+			},
+			"ca_cert_pem": schema.StringAttribute{
+				MarkdownDescription: "PEM encoded CA certificates to trust when connecting to GrackDB, in addition to the system's. Can also be set with the `GRACKDB_CA_CERT_PEM` environment variable.",
+				Optional:            true,
+			},
+			"ca_cert_file": schema.StringAttribute{
+				MarkdownDescription: "Path to a file of PEM encoded CA certificates to trust when connecting to GrackDB, in addition to the system's. Can also be set with the `GRACKDB_CA_CERT_FILE` environment variable.",
+				Optional:            true,
+			},
+			"client_cert": schema.StringAttribute{
+				MarkdownDescription: "PEM encoded client certificate to present when connecting to GrackDB, along with `client_key`. Can also be set with the `GRACKDB_CLIENT_CERT` environment variable.",
+				Optional:            true,
+			},
+			"client_key": schema.StringAttribute{
+				MarkdownDescription: "PEM encoded private key of `client_cert`. Can also be set with the `GRACKDB_CLIENT_KEY` environment variable.",
+				Optional:            true,
+				Sensitive:           true,
+			},
+			"insecure_skip_verify": schema.BoolAttribute{
+				MarkdownDescription: "Skip verifying the GrackDB server's TLS certificate. Only intended for testing. Defaults to `false`. Can also be set with the `GRACKDB_INSECURE_SKIP_VERIFY` environment variable.",
+				Optional:            true,
+			},
+			"proxy_url": schema.StringAttribute{
+				MarkdownDescription: "URL of the HTTP proxy to send API requests through. Defaults to the proxy configured by the standard `HTTPS_PROXY`, `HTTP_PROXY` and `NO_PROXY` environment variables. Can also be set with the `GRACKDB_PROXY_URL` environment variable.",
+				Optional:            true,
+			},
+			"headers": schema.MapAttribute{
+				MarkdownDescription: "Additional HTTP headers to send with every API request, such as for routing or Cloudflare Access. An `Authorization` header is refused unless `allow_authorization_header` is set. " +
+					"Can also be set with the `GRACKDB_HEADERS` environment variable, as a JSON object.",
+				ElementType: types.StringType,
+				Optional:    true,
+				Sensitive:   true,
+			},
+			"allow_authorization_header": schema.BoolAttribute{
+				MarkdownDescription: "Allow `headers` to set the `Authorization` header, replacing the API token. Defaults to `false`. Can also be set with the `GRACKDB_ALLOW_AUTHORIZATION_HEADER` environment variable.",
+				Optional:            true,
+			},
+			"validate_credentials": schema.BoolAttribute{
+				MarkdownDescription: "Look up the authenticated user when the provider is configured, failing immediately if GrackDB can't be reached or the token isn't valid. Defaults to `false`. Can also be set with the `GRACKDB_VALIDATE_CREDENTIALS` environment variable.",
+				Optional:            true,
+			},
+			"read_only": schema.BoolAttribute{
+				MarkdownDescription: "Refuse to create, update or delete any resource, failing at plan time, and refuse to send any GraphQL mutation. Intended for workspaces that should only ever read GrackDB. Defaults to `false`. Can also be set with the `GRACKDB_READ_ONLY` environment variable.",
+				Optional:            true,
+			},
+			"on_destroy": schema.StringAttribute{
+				MarkdownDescription: "What destroying a resource does to its record in GrackDB, unless the resource sets its own `on_destroy`: `delete` it, `archive` it, keeping its history, or `abandon` it, only removing it from Terraform state. Defaults to `delete`. Can also be set with the `GRACKDB_ON_DESTROY` environment variable.",
+				Optional:            true,
+			},
+			"otlp_endpoint": schema.StringAttribute{
+				MarkdownDescription: "URL of an OTLP/HTTP collector to export traces of provider operations to, such as `http://localhost:4318`. Can also be set with the `GRACKDB_OTLP_ENDPOINT` environment variable, and tracing enabled with the standard `OTEL_EXPORTER_OTLP_ENDPOINT` environment variables.",
+				Optional:            true,
+			},
+		},
+	}
+}
+
+func (p *grackdbProvider) Resources(ctx context.Context) []func() resource.Resource {
+	return []func() resource.Resource{
+		NewUserResource,
+		NewDiscordAccountResource,
+	}
+}
+
+func (p *grackdbProvider) DataSources(ctx context.Context) []func() datasource.DataSource {
+	return []func() datasource.DataSource{
+		NewCurrentUserDataSource,
+	}
+}
+
+func (p *grackdbProvider) Functions(ctx context.Context) []func() function.Function {
+	return []func() function.Function{
+		NewSnowflakeTimestampFunction,
+		NewSnowflakeToMentionFunction,
+		NewDiscordAvatarURLFunction,
 	}
 }
 
 type apiClient struct {
-	httpClient *http.Client
-	apiUrl     string
+	httpClient     *http.Client
+	apiUrl         string
+	credentials    *credentials
+	tracerProvider trace.TracerProvider
+
+	// persistedQueries enables sending operations by hash rather than by document.
+	persistedQueries bool
+
+	// readOnly prevents any mutation from being sent.
+	readOnly bool
+
+	// defaultOnDestroy is the on_destroy behaviour of resources that don't set their own.
+	defaultOnDestroy string
+
+	// limiter throttles requests sent to the API, nil when requests are unlimited.
+	limiter *rate.Limiter
+
+	// currentUser is the authenticated user, set when credentials were validated during Configure.
+	currentUser *grackdb.User
+
+	cache           *recordCache
+	users           *batchLoader[grackdb.User]
+	discordAccounts *batchLoader[grackdb.DiscordAccount]
 }
 
 type withHeaderType struct {
@@ -81,24 +256,278 @@ func (h withHeaderType) RoundTrip(req *http.Request) (*http.Response, error) {
 	return h.rt.RoundTrip(req)
 }
 
-func configure(version string, p *schema.Provider) func(context.Context, *schema.ResourceData) (interface{}, diag.Diagnostics) {
-	return func(ctx context.Context, d *schema.ResourceData) (interface{}, diag.Diagnostics) {
-		apiUrl := d.Get("api_url").(string)
-		token := d.Get("token").(string)
+func (p *grackdbProvider) Configure(ctx context.Context, req provider.ConfigureRequest, resp *provider.ConfigureResponse) {
+	var config grackdbProviderModel
+
+	resp.Diagnostics.Append(req.Config.Get(ctx, &config)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Falling back to defaults for these would send requests, and potentially a stored login
+	// token, to a different GrackDB instance than the one that will eventually be configured.
+	unknowns := []struct {
+		attribute string
+		value     types.String
+	}{
+		{"api_url", config.ApiUrl},
+		{"token", config.Token},
+		{"token_file", config.TokenFile},
+		{"credentials_helper", config.CredentialsHelper},
+	}
+	for _, unknown := range unknowns {
+		if unknown.value.IsUnknown() {
+			resp.Diagnostics.AddAttributeError(
+				path.Root(unknown.attribute),
+				"Unknown provider configuration",
+				fmt.Sprintf("The provider can't connect to GrackDB while %s is unknown. Set it statically in the configuration, remove it to use "+
+					"the GRACKDB_%s environment variable, or apply the resource it depends on first with -target.", unknown.attribute, strings.ToUpper(unknown.attribute)),
+			)
+		}
+	}
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	resp.Diagnostics.Append(applyDefaults(&config)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	apiUrl := stringWithDefault(config.ApiUrl, DefaultApiUrl)
+	token := config.Token.ValueString()
 
-		userAgent := p.UserAgent("terraform-provider-grackdb", version)
-		httpClient := http.DefaultClient
-		transport := withHeader(httpClient.Transport)
-		transport.Set("User-Agent", userAgent)
+	requestTimeout, err := time.ParseDuration(stringWithDefault(config.RequestTimeout, defaultRequestTimeout))
+	if err != nil {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("request_timeout"),
+			"Invalid request timeout",
+			err.Error(),
+		)
+		return
+	}
+	if requestTimeout <= 0 {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("request_timeout"),
+			"Invalid request timeout",
+			fmt.Sprintf("request_timeout must be greater than zero, got %s.", requestTimeout),
+		)
+		return
+	}
+
+	onDestroy := stringWithDefault(config.OnDestroy, onDestroyDelete)
+	if !validOnDestroy(onDestroy) {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("on_destroy"),
+			"Invalid on_destroy",
+			fmt.Sprintf("on_destroy must be one of %s, got %q.", strings.Join(onDestroyValues, ", "), onDestroy),
+		)
+		return
+	}
 
-		if token != "" {
-			transport.Set("Authorization", "Bearer "+token)
+	if !config.Burst.IsNull() && config.MaxRequestsPerSecond.IsNull() {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("burst"),
+			"Missing request rate",
+			"burst only applies when max_requests_per_second is set. Set max_requests_per_second too, or remove burst.",
+		)
+		return
+	}
+
+	var limiter *rate.Limiter
+	if !config.MaxRequestsPerSecond.IsNull() {
+		limit := config.MaxRequestsPerSecond.ValueFloat64()
+		if limit <= 0 {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("max_requests_per_second"),
+				"Invalid request rate",
+				"max_requests_per_second must be greater than zero.",
+			)
+			return
 		}
 
-		httpClient.Transport = transport
-		return &apiClient{
-			httpClient: httpClient,
-			apiUrl:     apiUrl,
-		}, nil
+		burst := int(math.Ceil(limit))
+		if !config.Burst.IsNull() {
+			burst = int(config.Burst.ValueInt64())
+		}
+		if burst < 1 {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("burst"),
+				"Invalid request burst",
+				"burst must be at least one.",
+			)
+			return
+		}
+
+		limiter = rate.NewLimiter(rate.Limit(limit), burst)
+	}
+
+	if !config.TokenFile.IsNull() && !config.CredentialsHelper.IsNull() {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("credentials_helper"),
+			"Conflicting credentials",
+			"Only one of token_file and credentials_helper can be set.",
+		)
+		return
+	}
+
+	requestUrl, socketPath, err := parseUnixSocketURL(apiUrl)
+	if err != nil {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("api_url"),
+			"Invalid API URL",
+			err.Error(),
+		)
+		return
+	}
+
+	// The base transport is built first so that refreshing a stored login token trusts the same
+	// CAs, presents the same client certificate and uses the same proxy as API requests.
+	baseTransport, diags := newBaseTransport(config, socketPath)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// The token is taken from token, token_file or credentials_helper, falling back to any
+	// stored by the login subcommand.
+	creds := &credentials{token: token, source: "token"}
+	if token == "" && !config.TokenFile.IsNull() {
+		creds, err = newCredentials(ctx, "token_file", tokenFromFile(config.TokenFile.ValueString()))
+		if err != nil {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("token_file"),
+				"Unable to read API token",
+				err.Error(),
+			)
+			return
+		}
+	} else if token == "" && !config.CredentialsHelper.IsNull() {
+		creds, err = newCredentials(ctx, "credentials_helper", tokenFromHelper(config.CredentialsHelper.ValueString(), apiUrl))
+		if err != nil {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("credentials_helper"),
+				"Unable to read API token",
+				err.Error(),
+			)
+			return
+		}
+	} else if token == "" {
+		creds, err = newCredentials(ctx, "login", tokenFromLogin(apiUrl, &http.Client{Transport: baseTransport, Timeout: requestTimeout}))
+		if err != nil {
+			resp.Diagnostics.AddError("Unable to read stored GrackDB credentials", err.Error())
+			return
+		}
 	}
+
+	userAgent := fmt.Sprintf("Terraform/%s (+https://www.terraform.io) terraform-provider-grackdb/%s", req.TerraformVersion, p.version)
+
+	httpClient := &http.Client{Timeout: requestTimeout}
+	transport := withHeader(withCompression(baseTransport, config.CompressRequests.ValueBool()))
+	transport.Set("User-Agent", userAgent)
+	transport.Set("X-GrackDB-Client", "terraform-provider-grackdb/"+p.version)
+
+	headers := map[string]string{}
+	resp.Diagnostics.Append(config.Headers.ElementsAs(ctx, &headers, false)...)
+	for name, value := range headers {
+		if !httpguts.ValidHeaderFieldName(name) || !httpguts.ValidHeaderFieldValue(value) {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("headers").AtMapKey(name),
+				"Invalid header",
+				fmt.Sprintf("%q is not a valid HTTP header.", name),
+			)
+			continue
+		}
+
+		if http.CanonicalHeaderKey(name) == "Authorization" && !config.AllowAuthorizationHeader.ValueBool() {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("headers").AtMapKey(name),
+				"Authorization header not allowed",
+				"Setting the Authorization header would replace the API token. Set allow_authorization_header to allow it.",
+			)
+			continue
+		}
+
+		transport.Set(name, value)
+	}
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	tracerProvider, err := newTracerProvider(ctx, config.OtlpEndpoint.ValueString(), p.version)
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to configure tracing", err.Error())
+		return
+	}
+
+	httpClient.Transport = otelhttp.NewTransport(
+		transport,
+		otelhttp.WithTracerProvider(tracerProvider),
+		otelhttp.WithPropagators(tracePropagator),
+	)
+	client := &apiClient{
+		httpClient:     httpClient,
+		apiUrl:         requestUrl,
+		credentials:    creds,
+		tracerProvider: tracerProvider,
+		limiter:        limiter,
+		cache:          newRecordCache(),
+	}
+	client.persistedQueries = config.PersistedQueries.ValueBool()
+	client.readOnly = config.ReadOnly.ValueBool()
+	client.defaultOnDestroy = onDestroy
+	client.users = newBatchLoader(client.cache, "User", client.fetchUsers)
+	client.discordAccounts = newBatchLoader(client.cache, "DiscordAccount", client.fetchDiscordAccounts)
+
+	if config.ValidateCredentials.ValueBool() {
+		if creds.current() == "" {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("token"),
+				"Missing GrackDB API token",
+				"validate_credentials is enabled but no token was provided. Set token, token_file or credentials_helper, or log in with `terraform-provider-grackdb login`.",
+			)
+			return
+		}
+
+		currentUser, err := client.fetchCurrentUser(ctx)
+		if err != nil {
+			resp.Diagnostics.AddError(
+				"Unable to connect to GrackDB",
+				fmt.Sprintf("Failed to look up the authenticated user at %s: %s", apiUrl, err),
+			)
+			return
+		}
+		if currentUser == nil {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("token"),
+				"Invalid GrackDB API token",
+				fmt.Sprintf("%s did not recognise the configured token. Please ensure you've provided a valid api token.", apiUrl),
+			)
+			return
+		}
+
+		client.currentUser = currentUser
+		tflog.Debug(ctx, "Validated GrackDB credentials", map[string]interface{}{
+			"user_id":  currentUser.ID,
+			"username": currentUser.Username,
+		})
+	}
+
+	tflog.Debug(ctx, "Configured GrackDB client", map[string]interface{}{
+		"api_url":      apiUrl,
+		"socket_path":  socketPath,
+		"token_source": creds.source,
+	})
+
+	resp.DataSourceData = client
+	resp.ResourceData = client
+}
+
+// stringWithDefault returns the value of a provider attribute, or def when it isn't set.
+func stringWithDefault(value types.String, def string) string {
+	if value.IsNull() || value.IsUnknown() {
+		return def
+	}
+
+	return value.ValueString()
 }
@@ -0,0 +1,229 @@
+package provider
+
+import (
+	"context"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fogo-sh/terraform-provider-grackdb/internal/auth"
+	"github.com/hashicorp/terraform-plugin-framework/providerserver"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+)
+
+// objectValue returns a value of typ with the given attributes set and the rest null.
+func objectValue(typ tftypes.Object, attributes map[string]tftypes.Value) tftypes.Value {
+	values := make(map[string]tftypes.Value, len(typ.AttributeTypes))
+	for name, attributeType := range typ.AttributeTypes {
+		if value, ok := attributes[name]; ok {
+			values[name] = value
+		} else {
+			values[name] = tftypes.NewValue(attributeType, nil)
+		}
+	}
+
+	return tftypes.NewValue(typ, values)
+}
+
+func dynamicValue(t *testing.T, typ tftypes.Type, value tftypes.Value) *tfprotov6.DynamicValue {
+	t.Helper()
+
+	dynamic, err := tfprotov6.NewDynamicValue(typ, value)
+	if err != nil {
+		t.Fatalf("unable to encode %s: %s", typ, err)
+	}
+
+	return &dynamic
+}
+
+func requireNoDiagnostics(t *testing.T, operation string, diagnostics []*tfprotov6.Diagnostic) {
+	t.Helper()
+
+	for _, d := range diagnostics {
+		if d.Severity == tfprotov6.DiagnosticSeverityError {
+			t.Fatalf("%s failed: %s: %s", operation, d.Summary, d.Detail)
+		}
+	}
+}
+
+// testProvider is a provider server configured against a fake GrackDB server.
+type testProvider struct {
+	server  tfprotov6.ProviderServer
+	schemas *tfprotov6.GetProviderSchemaResponse
+}
+
+// configureTestProvider starts a provider server and configures it with attributes, failing
+// the test if that reports any errors.
+// configureProvider configures a new provider with the given attributes, returning it along with
+// the diagnostics it reported.
+func configureProvider(t *testing.T, attributes map[string]tftypes.Value) (testProvider, []*tfprotov6.Diagnostic) {
+	t.Helper()
+
+	ctx := context.Background()
+	server, err := providerserver.NewProtocol6WithError(New("test")())()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	schemas, err := server.GetProviderSchema(ctx, &tfprotov6.GetProviderSchemaRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	providerType := schemas.Provider.ValueType().(tftypes.Object)
+
+	configured, err := server.ConfigureProvider(ctx, &tfprotov6.ConfigureProviderRequest{
+		TerraformVersion: "1.9.0",
+		Config:           dynamicValue(t, providerType, objectValue(providerType, attributes)),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return testProvider{server: server, schemas: schemas}, configured.Diagnostics
+}
+
+func configureTestProvider(t *testing.T, attributes map[string]tftypes.Value) testProvider {
+	t.Helper()
+
+	provider, diagnostics := configureProvider(t, attributes)
+	requireNoDiagnostics(t, "configure", diagnostics)
+
+	return provider
+}
+
+// hasAttributeError reports whether diagnostics include an error for the named attribute.
+func hasAttributeError(diagnostics []*tfprotov6.Diagnostic, attribute string) bool {
+	for _, d := range diagnostics {
+		if d.Severity == tfprotov6.DiagnosticSeverityError && d.Attribute.Equal(tftypes.NewAttributePath().WithAttributeName(attribute)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p testProvider) resourceType(typeName string) tftypes.Object {
+	return p.schemas.ResourceSchemas[typeName].ValueType().(tftypes.Object)
+}
+
+// applyResource plans and applies the change from priorState to config, returning the new state.
+func (p testProvider) applyResource(t *testing.T, typeName string, priorState tftypes.Value, config tftypes.Value) tftypes.Value {
+	t.Helper()
+
+	ctx := context.Background()
+	typ := p.resourceType(typeName)
+	priorValue := dynamicValue(t, typ, priorState)
+	configValue := dynamicValue(t, typ, config)
+
+	planned, err := p.server.PlanResourceChange(ctx, &tfprotov6.PlanResourceChangeRequest{
+		TypeName:         typeName,
+		PriorState:       priorValue,
+		ProposedNewState: configValue,
+		Config:           configValue,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	requireNoDiagnostics(t, "plan", planned.Diagnostics)
+
+	applied, err := p.server.ApplyResourceChange(ctx, &tfprotov6.ApplyResourceChangeRequest{
+		TypeName:     typeName,
+		PriorState:   priorValue,
+		PlannedState: planned.PlannedState,
+		Config:       configValue,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	requireNoDiagnostics(t, "apply", applied.Diagnostics)
+
+	newState, err := applied.NewState.Unmarshal(typ)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return newState
+}
+
+func TestConfigureUnknown(t *testing.T) {
+	for _, attribute := range []string{"api_url", "token", "token_file", "credentials_helper"} {
+		t.Run(attribute, func(t *testing.T) {
+			isolateEnvironment(t)
+
+			_, diagnostics := configureProvider(t, map[string]tftypes.Value{
+				attribute: tftypes.NewValue(tftypes.String, tftypes.UnknownValue),
+			})
+			if !hasAttributeError(diagnostics, attribute) {
+				t.Errorf("no error reported for unknown %s, got %v", attribute, diagnostics)
+			}
+		})
+	}
+}
+
+func TestConfigureLoginRefreshTLS(t *testing.T) {
+	isolateEnvironment(t)
+
+	var refreshed bool
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/oauth/token":
+			refreshed = true
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"access_token": "refreshed", "refresh_token": "refresh-1", "token_type": "Bearer", "expires_in": 3600}`))
+		case "/query":
+			if got := r.Header.Get("Authorization"); got != "Bearer refreshed" {
+				t.Errorf("GrackDB received Authorization %q, want the refreshed token", got)
+			}
+			w.Write([]byte(`{"data": {"currentUser": {"__typename": "User", "id": "u1", "username": "bob", "avatarUrl": null}}}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	apiUrl := server.URL + "/query"
+
+	credentialsPath, err := auth.CredentialsPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Dir(credentialsPath), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	stored := `{"` + apiUrl + `": {"access_token": "expired", "refresh_token": "refresh-0", "expiry": "2000-01-01T00:00:00Z"}}`
+	if err := os.WriteFile(credentialsPath, []byte(stored), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	// The stored token can only be refreshed by trusting the test server's certificate.
+	configureTestProvider(t, map[string]tftypes.Value{
+		"api_url":              tftypes.NewValue(tftypes.String, apiUrl),
+		"ca_cert_pem":          tftypes.NewValue(tftypes.String, string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}))),
+		"validate_credentials": tftypes.NewValue(tftypes.Bool, true),
+	})
+
+	if !refreshed {
+		t.Error("stored token wasn't refreshed")
+	}
+}
+
+func TestConfigureBurstWithoutRate(t *testing.T) {
+	isolateEnvironment(t)
+
+	_, diagnostics := configureProvider(t, map[string]tftypes.Value{
+		"token": tftypes.NewValue(tftypes.String, "token"),
+		"burst": tftypes.NewValue(tftypes.Number, 5),
+	})
+	if !hasAttributeError(diagnostics, "burst") {
+		t.Errorf("no error reported for burst without max_requests_per_second, got %v", diagnostics)
+	}
+
+	// burst is accepted along with a rate.
+	configureTestProvider(t, map[string]tftypes.Value{
+		"token":                   tftypes.NewValue(tftypes.String, "token"),
+		"max_requests_per_second": tftypes.NewValue(tftypes.Number, 1),
+		"burst":                   tftypes.NewValue(tftypes.Number, 5),
+	})
+}
@@ -1,373 +1,502 @@
 package provider
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
-	"io/ioutil"
+	"fmt"
+
+	grackdb "github.com/fogo-sh/terraform-provider-grackdb/internal/types"
+	"github.com/hashicorp/terraform-plugin-framework-timeouts/resource/timeouts"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/booldefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
 
-	"github.com/fogo-sh/terraform-provider-grackdb/internal/types"
-	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
-	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+var (
+	_ resource.Resource                = &discordAccountResource{}
+	_ resource.ResourceWithConfigure   = &discordAccountResource{}
+	_ resource.ResourceWithImportState = &discordAccountResource{}
+	_ resource.ResourceWithModifyPlan  = &discordAccountResource{}
 )
 
-func resourceDiscordAccount() *schema.Resource {
-	return &schema.Resource{
+func NewDiscordAccountResource() resource.Resource {
+	return &discordAccountResource{}
+}
+
+type discordAccountResource struct {
+	client *apiClient
+}
+
+type discordAccountResourceModel struct {
+	ID                 types.String   `tfsdk:"id"`
+	DiscordID          types.String   `tfsdk:"discord_id"`
+	Username           types.String   `tfsdk:"username"`
+	Discriminator      types.String   `tfsdk:"discriminator"`
+	GlobalName         types.String   `tfsdk:"global_name"`
+	Owner              types.String   `tfsdk:"owner"`
+	Bot                types.String   `tfsdk:"bot"`
+	DeletionProtection types.Bool     `tfsdk:"deletion_protection"`
+	OnDestroy          types.String   `tfsdk:"on_destroy"`
+	Timeouts           timeouts.Value `tfsdk:"timeouts"`
+}
+
+func (m *discordAccountResourceModel) fromAPI(account grackdb.DiscordAccount) {
+	m.ID = types.StringValue(account.ID)
+	m.DiscordID = types.StringValue(account.DiscordID)
+	m.Username = types.StringValue(account.Username)
+	// Configurations clear the global name by setting it to "", which GrackDB reports as null.
+	if account.GlobalName != nil || m.GlobalName.IsUnknown() || m.GlobalName.ValueString() != "" {
+		m.GlobalName = types.StringPointerValue(account.GlobalName)
+	}
+
+	// Accounts migrated to Discord's unique username system report a discriminator of "0",
+	// normalise it to null so configurations omitting it don't show a perpetual diff.
+	switch {
+	case account.Discriminator == "":
+		m.Discriminator = types.StringNull()
+	case account.Discriminator == "0" && m.Discriminator.ValueString() != "0":
+		m.Discriminator = types.StringNull()
+	default:
+		m.Discriminator = types.StringValue(account.Discriminator)
+	}
+
+	if account.Owner != nil {
+		m.Owner = types.StringValue(account.Owner.ID)
+	} else {
+		m.Owner = types.StringNull()
+	}
+
+	if account.Bot != nil {
+		m.Bot = types.StringValue(account.Bot.ID)
+	} else {
+		m.Bot = types.StringNull()
+	}
+}
+
+func (r *discordAccountResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_discord_account"
+}
+
+func (r *discordAccountResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
 		// This description is used by the documentation generator and the language server.
-		Description: "Create and manage a GrackDB Discord account.",
-
-		CreateContext: resourceDiscordAccountCreate,
-		ReadContext:   resourceDiscordAccountRead,
-		UpdateContext: resourceDiscordAccountUpdate,
-		DeleteContext: resourceDiscordAccountDelete,
-
-		Schema: map[string]*schema.Schema{
-			"id": {
-				Description: "Unique ID for this Discord account.",
-				Type:        schema.TypeString,
-				Computed:    true,
+		MarkdownDescription: "Create and manage a GrackDB Discord account.",
+
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				MarkdownDescription: "Unique ID for this Discord account.",
+				Computed:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"discord_id": schema.StringAttribute{
+				MarkdownDescription: "Discord snowflake for this account.",
+				Required:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
 			},
-			"discord_id": {
-				Description: "Discord snowflake for this account.",
-				Type:        schema.TypeString,
-				Required:    true,
-				ForceNew:    true,
+			"username": schema.StringAttribute{
+				MarkdownDescription: "Username for this account.",
+				Required:            true,
 			},
-			"username": {
-				Description: "Username for this account.",
-				Type:        schema.TypeString,
-				Required:    true,
+			"discriminator": schema.StringAttribute{
+				MarkdownDescription: "Discriminator for this account. Only needed for accounts that have not migrated to Discord's unique username system.",
+				Optional:            true,
 			},
-			"discriminator": {
-				Description: "Discriminator for this account.",
-				Type:        schema.TypeString,
-				Required:    true,
+			"global_name": schema.StringAttribute{
+				MarkdownDescription: "Global display name for this account. Left as GrackDB reports it when unset, set to `\"\"` to clear it.",
+				Optional:            true,
+				Computed:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
 			},
-			"owner": {
-				Description: "ID of the User that owns this account.",
-				Type:        schema.TypeString,
-				Optional:    true,
+			"owner": schema.StringAttribute{
+				MarkdownDescription: "ID of the User that owns this account.",
+				Optional:            true,
 			},
-			"bot": {
-				Description: "ID of the bot that owns this account.",
-				Type:        schema.TypeString,
-				Computed:    true,
+			"bot": schema.StringAttribute{
+				MarkdownDescription: "ID of the bot that owns this account.",
+				Computed:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
 			},
+			"deletion_protection": schema.BoolAttribute{
+				MarkdownDescription: "Prevent this Discord account from being deleted from GrackDB. Must be set to `false` and applied before the Discord account can be destroyed, including when it's replaced, unless `on_destroy` is `archive` or `abandon`. Defaults to `false`.",
+				Optional:            true,
+				Computed:            true,
+				Default:             booldefault.StaticBool(false),
+			},
+			"on_destroy": onDestroyAttribute("Discord account"),
+		},
+
+		Blocks: map[string]schema.Block{
+			"timeouts": timeouts.Block(ctx, timeouts.Opts{
+				Create: true,
+				Read:   true,
+				Update: true,
+				Delete: true,
+			}),
 		},
 	}
 }
 
+func (r *discordAccountResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	client, ok := req.ProviderData.(*apiClient)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *apiClient, got: %T.", req.ProviderData),
+		)
+		return
+	}
+
+	r.client = client
+}
+
+var createDiscordAccountOperation = newOperation("TerraformCreateDiscordAccount", `
+	mutation TerraformCreateDiscordAccount($input: CreateDiscordAccountInput!) {
+		createDiscordAccount(input: $input) {
+			...DiscordAccountFields
+		}
+	}
+`+grackdb.DiscordAccountFragment)
+
 type createDiscordAccountResp struct {
-	Data struct {
-		CreateDiscordAccount types.DiscordAccount `json:"createDiscordAccount"`
-	} `json:"data"`
+	CreateDiscordAccount grackdb.DiscordAccount `json:"createDiscordAccount"`
 }
 
-func resourceDiscordAccountCreate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
-	client := meta.(*apiClient)
+func (r *discordAccountResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	ctx, span := r.client.startSpan(ctx, "grackdb_discord_account.Create")
+	defer func() { r.client.endSpan(ctx, span, resp.Diagnostics) }()
 
-	variables := map[string]interface{}{
-		"discordId":     d.Get("discord_id").(string),
-		"username":      d.Get("username").(string),
-		"discriminator": d.Get("discriminator").(string),
-	}
-
-	owner := d.Get("owner").(string)
-	if owner != "" {
-		variables["owner"] = owner
-	}
-
-	reqBody, err := json.Marshal(map[string]interface{}{
-		"operationName": nil,
-		"query": `
-			mutation($input: CreateDiscordAccountInput!) {
-				createDiscordAccount(input: $input) {
-					id
-					discordId
-					username
-					discriminator
-					owner {
-						id
-						username
-						avatarUrl
-					}
-					bot {
-						id
-					}
-				}
-			}
-		`,
-		"variables": map[string]interface{}{
-			"input": variables,
-		},
-	})
-	if err != nil {
-		return diag.FromErr(err)
+	var plan discordAccountResourceModel
+
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
 	}
 
-	resp, err := client.httpClient.Post(
-		client.apiUrl,
-		"application/json",
-		bytes.NewBuffer(reqBody),
-	)
-	if err != nil {
-		return diag.FromErr(err)
+	createTimeout, diags := plan.Timeouts.Create(ctx, defaultResourceTimeout)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
 	}
-	defer resp.Body.Close()
 
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return diag.FromErr(err)
+	ctx, cancel := context.WithTimeout(ctx, createTimeout)
+	defer cancel()
+
+	variables := map[string]interface{}{
+		"discordId": plan.DiscordID.ValueString(),
+		"username":  plan.Username.ValueString(),
+	}
+
+	if !plan.Discriminator.IsNull() {
+		variables["discriminator"] = plan.Discriminator.ValueString()
+	}
+	if !plan.GlobalName.IsUnknown() && plan.GlobalName.ValueString() != "" {
+		variables["globalName"] = plan.GlobalName.ValueString()
+	}
+	if !plan.Owner.IsNull() {
+		variables["owner"] = plan.Owner.ValueString()
 	}
 
 	respData := new(createDiscordAccountResp)
-	err = json.Unmarshal(body, &respData)
+	err := r.client.execute(
+		ctx,
+		createDiscordAccountOperation,
+		map[string]interface{}{
+			"input": variables,
+		},
+		respData,
+	)
 	if err != nil {
-		return diag.FromErr(err)
+		resp.Diagnostics.AddError("Unable to create discord account", err.Error())
+		return
 	}
 
-	d.SetId(respData.Data.CreateDiscordAccount.ID)
-
-	return resourceDiscordAccountRead(ctx, d, meta)
-}
+	plan.fromAPI(respData.CreateDiscordAccount)
 
-type readDiscordAccountResp struct {
-	Data struct {
-		DiscordAccounts struct {
-			Edges []struct {
-				Node types.DiscordAccount
-			} `json:"edges"`
-		} `json:"DiscordAccounts"`
-	} `json:"data"`
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
 }
 
-func resourceDiscordAccountRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
-	client := meta.(*apiClient)
-
-	reqBody, err := json.Marshal(map[string]interface{}{
-		"operationName": nil,
-		"query": `
-			query($accountId: ID!) {
-				discordAccounts(where: { id: $accountId }) {
-					edges {
-						node {
-							id
-							discordId
-							username
-							discriminator
-							owner {
-								id
-								username
-								avatarUrl
-							}
-							bot {
-								id
-							}
-						}
-					}
+var readDiscordAccountsOperation = newOperation("TerraformReadDiscordAccounts", `
+	query TerraformReadDiscordAccounts($accountIds: [ID!]) {
+		discordAccounts(where: { idIn: $accountIds }) {
+			edges {
+				node {
+					...DiscordAccountFields
 				}
-			}		  
-		`,
-		"variables": map[string]interface{}{
-			"accountId": d.Id(),
-		},
-	})
-	if err != nil {
-		return diag.FromErr(err)
+			}
+		}
 	}
+`+grackdb.DiscordAccountFragment)
+
+type readDiscordAccountsResp struct {
+	DiscordAccounts struct {
+		Edges []struct {
+			Node grackdb.DiscordAccount
+		} `json:"edges"`
+	} `json:"discordAccounts"`
+}
 
-	resp, err := client.httpClient.Post(
-		client.apiUrl,
-		"application/json",
-		bytes.NewBuffer(reqBody),
+// fetchDiscordAccounts retrieves the Discord accounts with the given IDs in a single request, keyed by ID.
+func (c *apiClient) fetchDiscordAccounts(ctx context.Context, ids []string) (map[string]grackdb.DiscordAccount, error) {
+	respData := new(readDiscordAccountsResp)
+	err := c.execute(
+		ctx,
+		readDiscordAccountsOperation,
+		map[string]interface{}{
+			"accountIds": ids,
+		},
+		respData,
 	)
 	if err != nil {
-		return diag.FromErr(err)
+		return nil, err
 	}
-	defer resp.Body.Close()
 
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return diag.FromErr(err)
+	accounts := make(map[string]grackdb.DiscordAccount, len(respData.DiscordAccounts.Edges))
+	for _, edge := range respData.DiscordAccounts.Edges {
+		accounts[edge.Node.ID] = edge.Node
 	}
 
-	respData := new(readDiscordAccountResp)
-	err = json.Unmarshal(body, &respData)
-	if err != nil {
-		return diag.FromErr(err)
-	}
+	return accounts, nil
+}
 
-	if len(respData.Data.DiscordAccounts.Edges) == 0 {
-		return diag.Diagnostics{
-			diag.Diagnostic{
-				Summary: "Unable to refresh discord account state, unable to find requested account.",
-			},
-		}
-	}
+func (r *discordAccountResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	ctx, span := r.client.startSpan(ctx, "grackdb_discord_account.Read")
+	defer func() { r.client.endSpan(ctx, span, resp.Diagnostics) }()
 
-	account := respData.Data.DiscordAccounts.Edges[0].Node
-	if err = d.Set("id", account.ID); err != nil {
-		return diag.FromErr(err)
-	}
-	if err = d.Set("discord_id", account.DiscordID); err != nil {
-		return diag.FromErr(err)
+	var state discordAccountResourceModel
+
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
 	}
-	if err = d.Set("username", account.Username); err != nil {
-		return diag.FromErr(err)
+
+	readTimeout, diags := state.Timeouts.Read(ctx, defaultResourceTimeout)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
 	}
-	if err = d.Set("discriminator", account.Discriminator); err != nil {
-		return diag.FromErr(err)
+
+	ctx, cancel := context.WithTimeout(ctx, readTimeout)
+	defer cancel()
+
+	account, found, err := r.client.discordAccounts.load(ctx, state.ID.ValueString())
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to refresh discord account state", err.Error())
+		return
 	}
 
-	if account.Owner != nil {
-		if err = d.Set("owner", account.Owner.ID); err != nil {
-			return diag.FromErr(err)
-		}
+	if !found {
+		resp.Diagnostics.AddError(
+			"Unable to refresh discord account state",
+			"Unable to find requested account.",
+		)
+		return
 	}
 
-	if account.Bot != nil {
-		if err = d.Set("bot", account.Bot.ID); err != nil {
-			return diag.FromErr(err)
-		}
+	state.fromAPI(account)
+	if state.DeletionProtection.IsNull() {
+		// Imported resources, and those created before deletion_protection was added.
+		state.DeletionProtection = types.BoolValue(false)
 	}
 
-	return diag.Diagnostics{}
+	resp.Diagnostics.Append(resp.State.Set(ctx, &state)...)
 }
 
+var updateDiscordAccountOperation = newOperation("TerraformUpdateDiscordAccount", `
+	mutation TerraformUpdateDiscordAccount($accountId: ID!, $input: UpdateDiscordAccountInput!) {
+		updateDiscordAccount(id: $accountId, input: $input) {
+			...DiscordAccountFields
+		}
+	}
+`+grackdb.DiscordAccountFragment)
+
 type updateDiscordAccountResp struct {
-	Data struct {
-		UpdateDiscordAccount types.DiscordAccount `json:"updateDiscordAccount"`
-	} `json:"data"`
+	UpdateDiscordAccount grackdb.DiscordAccount `json:"updateDiscordAccount"`
 }
 
-func resourceDiscordAccountUpdate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
-	client := meta.(*apiClient)
+func (r *discordAccountResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	ctx, span := r.client.startSpan(ctx, "grackdb_discord_account.Update")
+	defer func() { r.client.endSpan(ctx, span, resp.Diagnostics) }()
 
-	variables := map[string]interface{}{}
+	var plan, state discordAccountResourceModel
 
-	if d.HasChange("username") {
-		variables["username"] = d.Get("username").(string)
-	}
-	if d.HasChange("discriminator") {
-		variables["discriminator"] = d.Get("discriminator").(string)
-	}
-	// TODO: Fix unsetting owner
-	if d.HasChange("owner") {
-		variables["owner"] = d.Get("owner").(string)
-	}
-
-	reqBody, err := json.Marshal(map[string]interface{}{
-		"operationName": nil,
-		"query": `
-			mutation($accountId: ID!, $input: UpdateDiscordAccountInput!) {
-				updateDiscordAccount(id: $accountId, input: $input) {
-					id
-					discordId
-					username
-					discriminator
-					owner {
-						id
-						username
-						avatarUrl
-					}
-					bot {
-						id
-					}
-				}
-		  	}
-		`,
-		"variables": map[string]interface{}{
-			"accountId": d.Id(),
-			"input":     variables,
-		},
-	})
-	if err != nil {
-		return diag.FromErr(err)
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
 	}
 
-	resp, err := client.httpClient.Post(
-		client.apiUrl,
-		"application/json",
-		bytes.NewBuffer(reqBody),
-	)
-	if err != nil {
-		return diag.FromErr(err)
+	updateTimeout, diags := plan.Timeouts.Update(ctx, defaultResourceTimeout)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
 	}
-	defer resp.Body.Close()
 
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return diag.FromErr(err)
+	ctx, cancel := context.WithTimeout(ctx, updateTimeout)
+	defer cancel()
+
+	variables := map[string]interface{}{}
+
+	if !plan.Username.Equal(state.Username) {
+		variables["username"] = plan.Username.ValueString()
+	}
+	if !plan.Discriminator.Equal(state.Discriminator) {
+		if plan.Discriminator.IsNull() {
+			variables["discriminator"] = "0"
+		} else {
+			variables["discriminator"] = plan.Discriminator.ValueString()
+		}
+	}
+	if !plan.GlobalName.IsUnknown() && !plan.GlobalName.Equal(state.GlobalName) {
+		if plan.GlobalName.ValueString() == "" {
+			variables["clearGlobalName"] = true
+		} else {
+			variables["globalName"] = plan.GlobalName.ValueString()
+		}
+	}
+	if !plan.Owner.Equal(state.Owner) {
+		if plan.Owner.IsNull() {
+			variables["clearOwner"] = true
+		} else {
+			variables["owner"] = plan.Owner.ValueString()
+		}
+	}
+
+	if len(variables) == 0 {
+		// Only settings local to Terraform, such as deletion_protection, have changed.
+		resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+		return
 	}
 
 	respData := new(updateDiscordAccountResp)
-	err = json.Unmarshal(body, &respData)
+	err := r.client.execute(
+		ctx,
+		updateDiscordAccountOperation,
+		map[string]interface{}{
+			"accountId": state.ID.ValueString(),
+			"input":     variables,
+		},
+		respData,
+	)
 	if err != nil {
-		return diag.FromErr(err)
+		resp.Diagnostics.AddError("Unable to update discord account", err.Error())
+		return
 	}
 
-	return resourceDiscordAccountRead(ctx, d, meta)
+	plan.fromAPI(respData.UpdateDiscordAccount)
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
 }
 
+var deleteDiscordAccountOperation = newOperation("TerraformDeleteDiscordAccount", `
+	mutation TerraformDeleteDiscordAccount($accountId: ID!) {
+		deleteDiscordAccount(id: $accountId) {
+			...DiscordAccountFields
+		}
+	}
+`+grackdb.DiscordAccountFragment)
+
 type deleteDiscordAccountResp struct {
-	Data struct {
-		DeleteDiscordAccount types.DiscordAccount `json:"deleteDiscordAccount"`
-	} `json:"data"`
+	DeleteDiscordAccount grackdb.DiscordAccount `json:"deleteDiscordAccount"`
 }
 
-func resourceDiscordAccountDelete(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
-	client := meta.(*apiClient)
-
-	reqBody, err := json.Marshal(map[string]interface{}{
-		"operationName": nil,
-		"query": `
-			mutation($accountId: ID!) {
-				deleteDiscordAccount(id: $accountId) {
-					id
-					discordId
-					username
-					discriminator
-					owner {
-						id
-						username
-						avatarUrl
-					}
-					bot {
-						id
-					}
-				}
-			}
-		`,
-		"variables": map[string]interface{}{
-			"accountId": d.Id(),
-		},
-	})
-	if err != nil {
-		return diag.FromErr(err)
+var archiveDiscordAccountOperation = newOperation("TerraformArchiveDiscordAccount", `
+	mutation TerraformArchiveDiscordAccount($accountId: ID!) {
+		archiveDiscordAccount(id: $accountId) {
+			...DiscordAccountFields
+		}
 	}
+`+grackdb.DiscordAccountFragment)
 
-	resp, err := client.httpClient.Post(
-		client.apiUrl,
-		"application/json",
-		bytes.NewBuffer(reqBody),
-	)
-	if err != nil {
-		return diag.FromErr(err)
+type archiveDiscordAccountResp struct {
+	ArchiveDiscordAccount grackdb.DiscordAccount `json:"archiveDiscordAccount"`
+}
+
+func (r *discordAccountResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	ctx, span := r.client.startSpan(ctx, "grackdb_discord_account.Delete")
+	defer func() { r.client.endSpan(ctx, span, resp.Diagnostics) }()
+
+	var state discordAccountResourceModel
+
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
 	}
-	defer resp.Body.Close()
 
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return diag.FromErr(err)
+	onDestroy := r.client.onDestroy(state.OnDestroy)
+	if onDestroy == onDestroyAbandon {
+		tflog.Info(ctx, "Abandoning Discord account, leaving it in GrackDB", map[string]interface{}{
+			"id": state.ID.ValueString(),
+		})
+		return
+	}
+
+	if onDestroy == onDestroyDelete && state.DeletionProtection.ValueBool() {
+		resp.Diagnostics.AddError(
+			"Discord account is protected from deletion",
+			fmt.Sprintf("Discord account %s has deletion_protection enabled. Set deletion_protection = false and apply before destroying it.", state.ID.ValueString()),
+		)
+		return
+	}
+
+	deleteTimeout, diags := state.Timeouts.Delete(ctx, defaultResourceTimeout)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, deleteTimeout)
+	defer cancel()
+
+	if onDestroy == onDestroyArchive {
+		err := r.client.execute(
+			ctx,
+			archiveDiscordAccountOperation,
+			map[string]interface{}{
+				"accountId": state.ID.ValueString(),
+			},
+			new(archiveDiscordAccountResp),
+		)
+		if err != nil {
+			resp.Diagnostics.AddError("Unable to archive discord account", err.Error())
+		}
+		return
 	}
 
 	respData := new(deleteDiscordAccountResp)
-	err = json.Unmarshal(body, &respData)
+	err := r.client.execute(
+		ctx,
+		deleteDiscordAccountOperation,
+		map[string]interface{}{
+			"accountId": state.ID.ValueString(),
+		},
+		respData,
+	)
 	if err != nil {
-		return diag.FromErr(err)
+		resp.Diagnostics.AddError("Unable to delete discord account", err.Error())
+		return
 	}
+}
 
-	d.SetId("")
+func (r *discordAccountResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	r.client.modifyPlan(ctx, "grackdb_discord_account", req, resp)
+}
 
-	return diag.Diagnostics{}
+func (r *discordAccountResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	resource.ImportStatePassthroughID(ctx, path.Root("id"), req, resp)
 }
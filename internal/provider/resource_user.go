@@ -1,304 +1,424 @@
 package provider
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
-	"io/ioutil"
+	"fmt"
+
+	grackdb "github.com/fogo-sh/terraform-provider-grackdb/internal/types"
+	"github.com/hashicorp/terraform-plugin-framework-timeouts/resource/timeouts"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/booldefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
 
-	"github.com/fogo-sh/terraform-provider-grackdb/internal/types"
-	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
-	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+var (
+	_ resource.Resource                = &userResource{}
+	_ resource.ResourceWithConfigure   = &userResource{}
+	_ resource.ResourceWithImportState = &userResource{}
+	_ resource.ResourceWithModifyPlan  = &userResource{}
 )
 
-func resourceUser() *schema.Resource {
-	return &schema.Resource{
+func NewUserResource() resource.Resource {
+	return &userResource{}
+}
+
+type userResource struct {
+	client *apiClient
+}
+
+type userResourceModel struct {
+	ID                 types.String   `tfsdk:"id"`
+	Username           types.String   `tfsdk:"username"`
+	AvatarURL          types.String   `tfsdk:"avatar_url"`
+	DeletionProtection types.Bool     `tfsdk:"deletion_protection"`
+	OnDestroy          types.String   `tfsdk:"on_destroy"`
+	Timeouts           timeouts.Value `tfsdk:"timeouts"`
+}
+
+func (m *userResourceModel) fromAPI(user grackdb.User) {
+	m.ID = types.StringValue(user.ID)
+	m.Username = types.StringValue(user.Username)
+	m.AvatarURL = types.StringPointerValue(user.AvatarURL)
+}
+
+func (r *userResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_user"
+}
+
+func (r *userResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
 		// This description is used by the documentation generator and the language server.
-		Description: "Create and manage a GrackDB User.",
-
-		CreateContext: resourceUserCreate,
-		ReadContext:   resourceUserRead,
-		UpdateContext: resourceUserUpdate,
-		DeleteContext: resourceUserDelete,
-
-		Schema: map[string]*schema.Schema{
-			"id": {
-				Description: "Unique ID for this user.",
-				Type:        schema.TypeString,
-				Computed:    true,
+		MarkdownDescription: "Create and manage a GrackDB User.",
+
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				MarkdownDescription: "Unique ID for this user.",
+				Computed:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"username": schema.StringAttribute{
+				MarkdownDescription: "Username for this user.",
+				Required:            true,
 			},
-			"username": {
-				Description: "Username for this user.",
-				Type:        schema.TypeString,
-				Required:    true,
+			"avatar_url": schema.StringAttribute{
+				MarkdownDescription: "URL to this user's avatar.",
+				Optional:            true,
 			},
-			"avatar_url": {
-				Description: "URL to this user's avatar.",
-				Type:        schema.TypeString,
-				Optional:    true,
+			"deletion_protection": schema.BoolAttribute{
+				MarkdownDescription: "Prevent this user from being deleted from GrackDB. Must be set to `false` and applied before the user can be destroyed, including when it's replaced, unless `on_destroy` is `archive` or `abandon`. Defaults to `true`.",
+				Optional:            true,
+				Computed:            true,
+				Default:             booldefault.StaticBool(true),
 			},
+			"on_destroy": onDestroyAttribute("user"),
+		},
+
+		Blocks: map[string]schema.Block{
+			"timeouts": timeouts.Block(ctx, timeouts.Opts{
+				Create: true,
+				Read:   true,
+				Update: true,
+				Delete: true,
+			}),
 		},
 	}
 }
 
+func (r *userResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	client, ok := req.ProviderData.(*apiClient)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *apiClient, got: %T.", req.ProviderData),
+		)
+		return
+	}
+
+	r.client = client
+}
+
+var createUserOperation = newOperation("TerraformCreateUser", `
+	mutation TerraformCreateUser($input: CreateUserInput!) {
+		createUser(input: $input) {
+			...UserFields
+		}
+	}
+`+grackdb.UserFragment)
+
 type createUserResp struct {
-	Data struct {
-		CreateUser types.User `json:"createUser"`
-	} `json:"data"`
+	CreateUser grackdb.User `json:"createUser"`
 }
 
-func resourceUserCreate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
-	client := meta.(*apiClient)
+func (r *userResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	ctx, span := r.client.startSpan(ctx, "grackdb_user.Create")
+	defer func() { r.client.endSpan(ctx, span, resp.Diagnostics) }()
 
-	variables := map[string]interface{}{
-		"username": d.Get("username").(string),
-	}
+	var plan userResourceModel
 
-	avatarUrl := d.Get("avatar_url").(string)
-	if avatarUrl != "" {
-		variables["avatarUrl"] = avatarUrl
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
 	}
 
-	reqBody, err := json.Marshal(map[string]interface{}{
-		"operationName": nil,
-		"query": `
-			mutation($input: CreateUserInput!) {
-				createUser(input: $input) {
-			  		id
-			  		username
-			  		avatarUrl
-				}
-		  	}
-		`,
-		"variables": map[string]interface{}{
-			"input": variables,
-		},
-	})
-	if err != nil {
-		return diag.FromErr(err)
+	createTimeout, diags := plan.Timeouts.Create(ctx, defaultResourceTimeout)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
 	}
 
-	resp, err := client.httpClient.Post(
-		client.apiUrl,
-		"application/json",
-		bytes.NewBuffer(reqBody),
-	)
-	if err != nil {
-		return diag.FromErr(err)
+	ctx, cancel := context.WithTimeout(ctx, createTimeout)
+	defer cancel()
+
+	variables := map[string]interface{}{
+		"username": plan.Username.ValueString(),
 	}
-	defer resp.Body.Close()
 
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return diag.FromErr(err)
+	if !plan.AvatarURL.IsNull() {
+		variables["avatarUrl"] = plan.AvatarURL.ValueString()
 	}
 
 	respData := new(createUserResp)
-	err = json.Unmarshal(body, &respData)
+	err := r.client.execute(
+		ctx,
+		createUserOperation,
+		map[string]interface{}{
+			"input": variables,
+		},
+		respData,
+	)
 	if err != nil {
-		return diag.FromErr(err)
+		resp.Diagnostics.AddError("Unable to create user", err.Error())
+		return
 	}
 
-	d.SetId(respData.Data.CreateUser.ID)
-
-	return resourceUserRead(ctx, d, meta)
-}
+	plan.fromAPI(respData.CreateUser)
 
-type readUserResp struct {
-	Data struct {
-		Users struct {
-			Edges []struct {
-				Node types.User
-			} `json:"edges"`
-		} `json:"users"`
-	} `json:"data"`
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
 }
 
-func resourceUserRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
-	client := meta.(*apiClient)
-
-	reqBody, err := json.Marshal(map[string]interface{}{
-		"operationName": nil,
-		"query": `
-			query($userId: ID!) {
-				users(where: { id: $userId }) {
-			  		edges {
-						node {
-				  			id
-				  			username
-						}
-			  		}
+var readUsersOperation = newOperation("TerraformReadUsers", `
+	query TerraformReadUsers($userIds: [ID!]) {
+		users(where: { idIn: $userIds }) {
+			edges {
+				node {
+					...UserFields
 				}
 			}
-		`,
-		"variables": map[string]interface{}{
-			"userId": d.Id(),
-		},
-	})
-	if err != nil {
-		return diag.FromErr(err)
+		}
 	}
+`+grackdb.UserFragment)
+
+type readUsersResp struct {
+	Users struct {
+		Edges []struct {
+			Node grackdb.User
+		} `json:"edges"`
+	} `json:"users"`
+}
 
-	resp, err := client.httpClient.Post(
-		client.apiUrl,
-		"application/json",
-		bytes.NewBuffer(reqBody),
+// fetchUsers retrieves the users with the given IDs in a single request, keyed by ID.
+func (c *apiClient) fetchUsers(ctx context.Context, ids []string) (map[string]grackdb.User, error) {
+	respData := new(readUsersResp)
+	err := c.execute(
+		ctx,
+		readUsersOperation,
+		map[string]interface{}{
+			"userIds": ids,
+		},
+		respData,
 	)
 	if err != nil {
-		return diag.FromErr(err)
+		return nil, err
 	}
-	defer resp.Body.Close()
 
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return diag.FromErr(err)
+	users := make(map[string]grackdb.User, len(respData.Users.Edges))
+	for _, edge := range respData.Users.Edges {
+		users[edge.Node.ID] = edge.Node
 	}
 
-	respData := new(readUserResp)
-	err = json.Unmarshal(body, &respData)
-	if err != nil {
-		return diag.FromErr(err)
+	return users, nil
+}
+
+func (r *userResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	ctx, span := r.client.startSpan(ctx, "grackdb_user.Read")
+	defer func() { r.client.endSpan(ctx, span, resp.Diagnostics) }()
+
+	var state userResourceModel
+
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
 	}
 
-	if len(respData.Data.Users.Edges) == 0 {
-		return diag.Diagnostics{
-			diag.Diagnostic{
-				Summary: "Unable to refresh user state, unable to find requested user.",
-			},
-		}
+	readTimeout, diags := state.Timeouts.Read(ctx, defaultResourceTimeout)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
 	}
 
-	user := respData.Data.Users.Edges[0].Node
-	if err = d.Set("id", user.ID); err != nil {
-		return diag.FromErr(err)
+	ctx, cancel := context.WithTimeout(ctx, readTimeout)
+	defer cancel()
+
+	user, found, err := r.client.users.load(ctx, state.ID.ValueString())
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to refresh user state", err.Error())
+		return
 	}
-	if err = d.Set("username", user.Username); err != nil {
-		return diag.FromErr(err)
+
+	if !found {
+		resp.Diagnostics.AddError(
+			"Unable to refresh user state",
+			"Unable to find requested user.",
+		)
+		return
 	}
-	if err = d.Set("avatar_url", user.AvatarURL); err != nil {
-		return diag.FromErr(err)
+
+	state.fromAPI(user)
+	if state.DeletionProtection.IsNull() {
+		// Imported resources, and those created before deletion_protection was added.
+		state.DeletionProtection = types.BoolValue(true)
 	}
 
-	return diag.Diagnostics{}
+	resp.Diagnostics.Append(resp.State.Set(ctx, &state)...)
 }
 
+var updateUserOperation = newOperation("TerraformUpdateUser", `
+	mutation TerraformUpdateUser($userId: ID!, $input: UpdateUserInput!) {
+		updateUser(id: $userId, input: $input) {
+			...UserFields
+		}
+	}
+`+grackdb.UserFragment)
+
 type updateUserResp struct {
-	Data struct {
-		UpdateUser types.User `json:"updateUser"`
-	} `json:"data"`
+	UpdateUser grackdb.User `json:"updateUser"`
 }
 
-func resourceUserUpdate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
-	client := meta.(*apiClient)
+func (r *userResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	ctx, span := r.client.startSpan(ctx, "grackdb_user.Update")
+	defer func() { r.client.endSpan(ctx, span, resp.Diagnostics) }()
+
+	var plan, state userResourceModel
+
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	updateTimeout, diags := plan.Timeouts.Update(ctx, defaultResourceTimeout)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, updateTimeout)
+	defer cancel()
 
 	variables := map[string]interface{}{}
 
-	if d.HasChange("username") {
-		variables["username"] = d.Get("username").(string)
+	if !plan.Username.Equal(state.Username) {
+		variables["username"] = plan.Username.ValueString()
 	}
-	if d.HasChange("avatar_url") {
-		avatarUrlVal := d.Get("avatar_url").(string)
-		avatarUrl := &avatarUrlVal
-		if avatarUrlVal == "" {
-			avatarUrl = nil
+	if !plan.AvatarURL.Equal(state.AvatarURL) {
+		if plan.AvatarURL.IsNull() {
+			variables["clearAvatarUrl"] = true
+		} else {
+			variables["avatarUrl"] = plan.AvatarURL.ValueString()
 		}
-		variables["avatarUrl"] = avatarUrl
 	}
 
-	reqBody, err := json.Marshal(map[string]interface{}{
-		"operationName": nil,
-		"query": `
-			mutation($userId: ID!, $input: UpdateUserInput!) {
-				updateUser(id: $userId, input: $input) {
-			  		id
-			  		username
-			  		avatarUrl
-				}
-		  	}
-		`,
-		"variables": map[string]interface{}{
-			"userId": d.Id(),
-			"input":  variables,
-		},
-	})
-	if err != nil {
-		return diag.FromErr(err)
+	if len(variables) == 0 {
+		// Only settings local to Terraform, such as deletion_protection, have changed.
+		resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+		return
 	}
 
-	resp, err := client.httpClient.Post(
-		client.apiUrl,
-		"application/json",
-		bytes.NewBuffer(reqBody),
+	respData := new(updateUserResp)
+	err := r.client.execute(
+		ctx,
+		updateUserOperation,
+		map[string]interface{}{
+			"userId": state.ID.ValueString(),
+			"input":  variables,
+		},
+		respData,
 	)
 	if err != nil {
-		return diag.FromErr(err)
+		resp.Diagnostics.AddError("Unable to update user", err.Error())
+		return
 	}
-	defer resp.Body.Close()
 
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return diag.FromErr(err)
-	}
+	plan.fromAPI(respData.UpdateUser)
 
-	respData := new(updateUserResp)
-	err = json.Unmarshal(body, &respData)
-	if err != nil {
-		return diag.FromErr(err)
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+}
+
+var deleteUserOperation = newOperation("TerraformDeleteUser", `
+	mutation TerraformDeleteUser($userId: ID!) {
+		deleteUser(id: $userId) {
+			...UserFields
+		}
 	}
+`+grackdb.UserFragment)
 
-	return resourceUserRead(ctx, d, meta)
+type deleteUserResp struct {
+	DeleteUser grackdb.User `json:"deleteUser"`
 }
 
-type deleteUserResp struct {
-	Data struct {
-		DeleteUser types.User `json:"deleteUser"`
-	} `json:"data"`
+var archiveUserOperation = newOperation("TerraformArchiveUser", `
+	mutation TerraformArchiveUser($userId: ID!) {
+		archiveUser(id: $userId) {
+			...UserFields
+		}
+	}
+`+grackdb.UserFragment)
+
+type archiveUserResp struct {
+	ArchiveUser grackdb.User `json:"archiveUser"`
 }
 
-func resourceUserDelete(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
-	client := meta.(*apiClient)
-
-	reqBody, err := json.Marshal(map[string]interface{}{
-		"operationName": nil,
-		"query": `
-			mutation($userId: ID!) {
-				deleteUser(id: $userId) {
-					id
-					username
-					avatarUrl
-				}
-			}
-		`,
-		"variables": map[string]interface{}{
-			"userId": d.Id(),
-		},
-	})
-	if err != nil {
-		return diag.FromErr(err)
+func (r *userResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	ctx, span := r.client.startSpan(ctx, "grackdb_user.Delete")
+	defer func() { r.client.endSpan(ctx, span, resp.Diagnostics) }()
+
+	var state userResourceModel
+
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
 	}
 
-	resp, err := client.httpClient.Post(
-		client.apiUrl,
-		"application/json",
-		bytes.NewBuffer(reqBody),
-	)
-	if err != nil {
-		return diag.FromErr(err)
+	onDestroy := r.client.onDestroy(state.OnDestroy)
+	if onDestroy == onDestroyAbandon {
+		tflog.Info(ctx, "Abandoning user, leaving it in GrackDB", map[string]interface{}{
+			"id": state.ID.ValueString(),
+		})
+		return
 	}
-	defer resp.Body.Close()
 
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return diag.FromErr(err)
+	if onDestroy == onDestroyDelete && state.DeletionProtection.ValueBool() {
+		resp.Diagnostics.AddError(
+			"User is protected from deletion",
+			fmt.Sprintf("User %s has deletion_protection enabled. Set deletion_protection = false and apply before destroying it.", state.ID.ValueString()),
+		)
+		return
+	}
+
+	deleteTimeout, diags := state.Timeouts.Delete(ctx, defaultResourceTimeout)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, deleteTimeout)
+	defer cancel()
+
+	if onDestroy == onDestroyArchive {
+		err := r.client.execute(
+			ctx,
+			archiveUserOperation,
+			map[string]interface{}{
+				"userId": state.ID.ValueString(),
+			},
+			new(archiveUserResp),
+		)
+		if err != nil {
+			resp.Diagnostics.AddError("Unable to archive user", err.Error())
+		}
+		return
 	}
 
 	respData := new(deleteUserResp)
-	err = json.Unmarshal(body, &respData)
+	err := r.client.execute(
+		ctx,
+		deleteUserOperation,
+		map[string]interface{}{
+			"userId": state.ID.ValueString(),
+		},
+		respData,
+	)
 	if err != nil {
-		return diag.FromErr(err)
+		resp.Diagnostics.AddError("Unable to delete user", err.Error())
+		return
 	}
+}
 
-	d.SetId("")
+func (r *userResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	r.client.modifyPlan(ctx, "grackdb_user", req, resp)
+}
 
-	return diag.Diagnostics{}
+func (r *userResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	resource.ImportStatePassthroughID(ctx, path.Root("id"), req, resp)
 }
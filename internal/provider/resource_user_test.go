@@ -0,0 +1,99 @@
+package provider
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+)
+
+func TestUserUpdateAvatarURL(t *testing.T) {
+	tests := []struct {
+		name      string
+		prior     interface{}
+		planned   interface{}
+		wantInput map[string]interface{}
+	}{
+		{
+			name:      "set",
+			prior:     nil,
+			planned:   "https://example.com/avatar.png",
+			wantInput: map[string]interface{}{"avatarUrl": "https://example.com/avatar.png"},
+		},
+		{
+			name:      "changed",
+			prior:     "https://example.com/old.png",
+			planned:   "https://example.com/avatar.png",
+			wantInput: map[string]interface{}{"avatarUrl": "https://example.com/avatar.png"},
+		},
+		{
+			name:      "removed",
+			prior:     "https://example.com/old.png",
+			planned:   nil,
+			wantInput: map[string]interface{}{"clearAvatarUrl": true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			isolateEnvironment(t)
+
+			var gotInput map[string]interface{}
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var payload struct {
+					Variables struct {
+						Input map[string]interface{} `json:"input"`
+					} `json:"variables"`
+				}
+				json.NewDecoder(r.Body).Decode(&payload)
+				gotInput = payload.Variables.Input
+
+				json.NewEncoder(w).Encode(map[string]interface{}{
+					"data": map[string]interface{}{
+						"updateUser": map[string]interface{}{
+							"__typename": "User",
+							"id":         "u1",
+							"username":   "bob",
+							"avatarUrl":  tt.planned,
+						},
+					},
+				})
+			}))
+			defer server.Close()
+
+			provider := configureTestProvider(t, map[string]tftypes.Value{
+				"api_url": tftypes.NewValue(tftypes.String, server.URL),
+				"token":   tftypes.NewValue(tftypes.String, "token"),
+			})
+
+			userType := provider.resourceType("grackdb_user")
+			prior := objectValue(userType, map[string]tftypes.Value{
+				"id":                  tftypes.NewValue(tftypes.String, "u1"),
+				"username":            tftypes.NewValue(tftypes.String, "bob"),
+				"avatar_url":          tftypes.NewValue(tftypes.String, tt.prior),
+				"deletion_protection": tftypes.NewValue(tftypes.Bool, true),
+			})
+			config := objectValue(userType, map[string]tftypes.Value{
+				"username":   tftypes.NewValue(tftypes.String, "bob"),
+				"avatar_url": tftypes.NewValue(tftypes.String, tt.planned),
+			})
+
+			newState := provider.applyResource(t, "grackdb_user", prior, config)
+
+			if !reflect.DeepEqual(gotInput, tt.wantInput) {
+				t.Errorf("updateUser input = %v, want %v", gotInput, tt.wantInput)
+			}
+
+			var attributes map[string]tftypes.Value
+			if err := newState.As(&attributes); err != nil {
+				t.Fatal(err)
+			}
+			if want := tftypes.NewValue(tftypes.String, tt.planned); !attributes["avatar_url"].Equal(want) {
+				t.Errorf("avatar_url = %s, want %s", attributes["avatar_url"], want)
+			}
+		})
+	}
+}
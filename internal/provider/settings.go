@@ -0,0 +1,313 @@
+package provider
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// defaultProfile is used when no profile is selected, if the config file defines it.
+const defaultProfile = "default"
+
+// configFileEnv overrides the location of the config file profiles are read from.
+const configFileEnv = "GRACKDB_CONFIG_FILE"
+
+// profile holds the settings of a single profile in the config file, which mirror the
+// provider's attributes.
+type profile struct {
+	ApiUrl                   *string           `toml:"api_url"`
+	Token                    *string           `toml:"token"`
+	TokenFile                *string           `toml:"token_file"`
+	CredentialsHelper        *string           `toml:"credentials_helper"`
+	OtlpEndpoint             *string           `toml:"otlp_endpoint"`
+	RequestTimeout           *string           `toml:"request_timeout"`
+	MaxRequestsPerSecond     *float64          `toml:"max_requests_per_second"`
+	Burst                    *int64            `toml:"burst"`
+	PersistedQueries         *bool             `toml:"persisted_queries"`
+	CompressRequests         *bool             `toml:"compress_requests"`
+	ValidateCredentials      *bool             `toml:"validate_credentials"`
+	ReadOnly                 *bool             `toml:"read_only"`
+	OnDestroy                *string           `toml:"on_destroy"`
+	CaCertPem                *string           `toml:"ca_cert_pem"`
+	CaCertFile               *string           `toml:"ca_cert_file"`
+	ClientCert               *string           `toml:"client_cert"`
+	ClientKey                *string           `toml:"client_key"`
+	InsecureSkipVerify       *bool             `toml:"insecure_skip_verify"`
+	ProxyUrl                 *string           `toml:"proxy_url"`
+	Headers                  map[string]string `toml:"headers"`
+	AllowAuthorizationHeader *bool             `toml:"allow_authorization_header"`
+}
+
+type configFile struct {
+	Profiles map[string]profile `toml:"profiles"`
+}
+
+// configFilePath returns the path of the config file profiles are read from.
+func configFilePath() (string, error) {
+	if path := os.Getenv(configFileEnv); path != "" {
+		return path, nil
+	}
+
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, "grackdb", "config.toml"), nil
+}
+
+// loadProfile reads the named profile from the config file. When name is empty the default
+// profile is used if there is one, otherwise an empty profile is returned.
+func loadProfile(name string) (profile, error) {
+	path, err := configFilePath()
+	if err != nil {
+		if name == "" {
+			return profile{}, nil
+		}
+		return profile{}, fmt.Errorf("unable to locate config file: %w", err)
+	}
+
+	var config configFile
+	metadata, err := toml.DecodeFile(path, &config)
+	if errors.Is(err, fs.ErrNotExist) && name == "" {
+		return profile{}, nil
+	}
+	if err != nil {
+		return profile{}, fmt.Errorf("unable to read config file: %w", err)
+	}
+
+	if undecoded := metadata.Undecoded(); len(undecoded) != 0 {
+		keys := make([]string, 0, len(undecoded))
+		for _, key := range undecoded {
+			keys = append(keys, key.String())
+		}
+		return profile{}, fmt.Errorf("unknown settings in %s: %s", path, strings.Join(keys, ", "))
+	}
+
+	if name == "" {
+		return config.Profiles[defaultProfile], nil
+	}
+
+	selected, ok := config.Profiles[name]
+	if !ok {
+		return profile{}, fmt.Errorf("profile %q is not defined in %s", name, path)
+	}
+
+	return selected, nil
+}
+
+// applyDefaults fills in attributes left unset in configuration, first from their GRACKDB_*
+// environment variable and then from the selected profile.
+func applyDefaults(config *grackdbProviderModel) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	defaultString(&config.Profile, "GRACKDB_PROFILE", nil)
+
+	selected, err := loadProfile(config.Profile.ValueString())
+	if err != nil {
+		diags.AddAttributeError(
+			path.Root("profile"),
+			"Unable to load profile",
+			err.Error(),
+		)
+		return diags
+	}
+
+	defaultString(&config.ApiUrl, "GRACKDB_API_URL", selected.ApiUrl)
+	defaultCredentials(config, selected)
+	defaultString(&config.OtlpEndpoint, "GRACKDB_OTLP_ENDPOINT", selected.OtlpEndpoint)
+	defaultString(&config.RequestTimeout, "GRACKDB_REQUEST_TIMEOUT", selected.RequestTimeout)
+	diags.Append(defaultFloat64(&config.MaxRequestsPerSecond, "max_requests_per_second", "GRACKDB_MAX_REQUESTS_PER_SECOND", selected.MaxRequestsPerSecond)...)
+	diags.Append(defaultInt64(&config.Burst, "burst", "GRACKDB_BURST", selected.Burst)...)
+	diags.Append(defaultBool(&config.PersistedQueries, "persisted_queries", "GRACKDB_PERSISTED_QUERIES", selected.PersistedQueries)...)
+	diags.Append(defaultBool(&config.CompressRequests, "compress_requests", "GRACKDB_COMPRESS_REQUESTS", selected.CompressRequests)...)
+	diags.Append(defaultBool(&config.ValidateCredentials, "validate_credentials", "GRACKDB_VALIDATE_CREDENTIALS", selected.ValidateCredentials)...)
+	diags.Append(defaultBool(&config.ReadOnly, "read_only", "GRACKDB_READ_ONLY", selected.ReadOnly)...)
+	defaultString(&config.OnDestroy, "GRACKDB_ON_DESTROY", selected.OnDestroy)
+	defaultString(&config.CaCertPem, "GRACKDB_CA_CERT_PEM", selected.CaCertPem)
+	defaultString(&config.CaCertFile, "GRACKDB_CA_CERT_FILE", selected.CaCertFile)
+	defaultString(&config.ClientCert, "GRACKDB_CLIENT_CERT", selected.ClientCert)
+	defaultString(&config.ClientKey, "GRACKDB_CLIENT_KEY", selected.ClientKey)
+	diags.Append(defaultBool(&config.InsecureSkipVerify, "insecure_skip_verify", "GRACKDB_INSECURE_SKIP_VERIFY", selected.InsecureSkipVerify)...)
+	defaultString(&config.ProxyUrl, "GRACKDB_PROXY_URL", selected.ProxyUrl)
+	diags.Append(defaultStringMap(&config.Headers, "headers", "GRACKDB_HEADERS", selected.Headers)...)
+	diags.Append(defaultBool(&config.AllowAuthorizationHeader, "allow_authorization_header", "GRACKDB_ALLOW_AUTHORIZATION_HEADER", selected.AllowAuthorizationHeader)...)
+
+	return diags
+}
+
+// lookupEnv returns the value of the environment variable env, treating empty values as unset.
+func lookupEnv(env string) (string, bool) {
+	value := os.Getenv(env)
+	return value, value != ""
+}
+
+// defaultCredentials fills in token, token_file and credentials_helper as a group, so that a
+// token from the environment or profile can't take precedence over a token_file or
+// credentials_helper configured elsewhere. The environment is only consulted when none are set
+// in configuration, and the profile when none are set in either.
+func defaultCredentials(config *grackdbProviderModel, selected profile) {
+	if !config.Token.IsNull() || !config.TokenFile.IsNull() || !config.CredentialsHelper.IsNull() {
+		return
+	}
+
+	defaultString(&config.Token, "GRACKDB_TOKEN", nil)
+	defaultString(&config.TokenFile, "GRACKDB_TOKEN_FILE", nil)
+	defaultString(&config.CredentialsHelper, "GRACKDB_CREDENTIALS_HELPER", nil)
+	if !config.Token.IsNull() || !config.TokenFile.IsNull() || !config.CredentialsHelper.IsNull() {
+		return
+	}
+
+	config.Token = types.StringPointerValue(selected.Token)
+	config.TokenFile = types.StringPointerValue(selected.TokenFile)
+	config.CredentialsHelper = types.StringPointerValue(selected.CredentialsHelper)
+}
+
+func defaultString(value *types.String, env string, fromProfile *string) {
+	if !value.IsNull() {
+		return
+	}
+
+	if envValue, ok := lookupEnv(env); ok {
+		*value = types.StringValue(envValue)
+	} else if fromProfile != nil {
+		*value = types.StringPointerValue(fromProfile)
+	}
+}
+
+func defaultBool(value *types.Bool, attribute string, env string, fromProfile *bool) diag.Diagnostics {
+	var diags diag.Diagnostics
+	if !value.IsNull() {
+		return diags
+	}
+
+	if envValue, ok := lookupEnv(env); ok {
+		parsed, err := strconv.ParseBool(envValue)
+		if err != nil {
+			diags.AddAttributeError(path.Root(attribute), "Invalid environment variable", fmt.Sprintf("%s must be true or false.", env))
+			return diags
+		}
+		*value = types.BoolValue(parsed)
+	} else if fromProfile != nil {
+		*value = types.BoolPointerValue(fromProfile)
+	}
+
+	return diags
+}
+
+func defaultFloat64(value *types.Float64, attribute string, env string, fromProfile *float64) diag.Diagnostics {
+	var diags diag.Diagnostics
+	if !value.IsNull() {
+		return diags
+	}
+
+	if envValue, ok := lookupEnv(env); ok {
+		parsed, err := strconv.ParseFloat(envValue, 64)
+		if err != nil {
+			diags.AddAttributeError(path.Root(attribute), "Invalid environment variable", fmt.Sprintf("%s must be a number.", env))
+			return diags
+		}
+		*value = types.Float64Value(parsed)
+	} else if fromProfile != nil {
+		*value = types.Float64PointerValue(fromProfile)
+	}
+
+	return diags
+}
+
+func defaultInt64(value *types.Int64, attribute string, env string, fromProfile *int64) diag.Diagnostics {
+	var diags diag.Diagnostics
+	if !value.IsNull() {
+		return diags
+	}
+
+	if envValue, ok := lookupEnv(env); ok {
+		parsed, err := strconv.ParseInt(envValue, 10, 64)
+		if err != nil {
+			diags.AddAttributeError(path.Root(attribute), "Invalid environment variable", fmt.Sprintf("%s must be a whole number.", env))
+			return diags
+		}
+		*value = types.Int64Value(parsed)
+	} else if fromProfile != nil {
+		*value = types.Int64PointerValue(fromProfile)
+	}
+
+	return diags
+}
+
+// defaultStringMap fills in a map of strings, which is read from env as a JSON object.
+func defaultStringMap(value *types.Map, attribute string, env string, fromProfile map[string]string) diag.Diagnostics {
+	var diags diag.Diagnostics
+	if !value.IsNull() {
+		return diags
+	}
+
+	elements := fromProfile
+	if envValue, ok := lookupEnv(env); ok {
+		elements = nil
+		if err := json.Unmarshal([]byte(envValue), &elements); err != nil {
+			diags.AddAttributeError(path.Root(attribute), "Invalid environment variable", fmt.Sprintf("%s must be a JSON object of strings.", env))
+			return diags
+		}
+	}
+	if elements == nil {
+		return diags
+	}
+
+	values := make(map[string]attr.Value, len(elements))
+	for k, v := range elements {
+		values[k] = types.StringValue(v)
+	}
+	*value = types.MapValueMust(types.StringType, values)
+
+	return diags
+}
+
+// LoginSettings returns the API URL and HTTP client the provider uses when only configured from
+// GRACKDB_* environment variables and the selected profile, so that the login subcommand stores
+// a token under the same URL and reaches it through the same CAs, client certificate and proxy.
+// profileName selects a profile as the profile attribute does, and apiUrl overrides the API URL
+// when set.
+func LoginSettings(profileName string, apiUrl string) (string, *http.Client, error) {
+	var config grackdbProviderModel
+	if profileName != "" {
+		config.Profile = types.StringValue(profileName)
+	}
+	if apiUrl != "" {
+		config.ApiUrl = types.StringValue(apiUrl)
+	}
+
+	if diags := applyDefaults(&config); diags.HasError() {
+		return "", nil, diagnosticsError(diags)
+	}
+	apiUrl = stringWithDefault(config.ApiUrl, DefaultApiUrl)
+
+	_, socketPath, err := parseUnixSocketURL(apiUrl)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid API URL: %w", err)
+	}
+	transport, diags := newBaseTransport(config, socketPath)
+	if diags.HasError() {
+		return "", nil, diagnosticsError(diags)
+	}
+
+	return apiUrl, &http.Client{Transport: transport}, nil
+}
+
+// diagnosticsError returns the first error in diags as an error, for callers outside Terraform.
+func diagnosticsError(diags diag.Diagnostics) error {
+	err := diags.Errors()[0]
+	return fmt.Errorf("%s: %s", err.Summary(), err.Detail())
+}
@@ -0,0 +1,186 @@
+package provider
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// isolateEnvironment stops the developer's own config file, stored credentials and GRACKDB_* or
+// OTEL_* environment variables from affecting a test of the provider's configuration.
+func isolateEnvironment(t *testing.T) {
+	t.Helper()
+
+	for _, env := range os.Environ() {
+		name, _, _ := strings.Cut(env, "=")
+		if strings.HasPrefix(name, "GRACKDB_") || strings.HasPrefix(name, "OTEL_") {
+			t.Setenv(name, "")
+		}
+	}
+
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+
+	configFile := filepath.Join(dir, "empty.toml")
+	if err := os.WriteFile(configFile, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(configFileEnv, configFile)
+}
+
+func TestApplyDefaultsCredentials(t *testing.T) {
+	isolateEnvironment(t)
+
+	configFile := filepath.Join(t.TempDir(), "config.toml")
+	err := os.WriteFile(configFile, []byte(`
+[profiles.default]
+token = "profile-token"
+
+[profiles.helper]
+credentials_helper = "profile-helper"
+`), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		config  grackdbProviderModel
+		env     map[string]string
+		profile string
+		want    [3]string
+	}{
+		{
+			name:   "config token_file over env token",
+			config: grackdbProviderModel{TokenFile: types.StringValue("/config/token")},
+			env:    map[string]string{"GRACKDB_TOKEN": "env-token"},
+			want:   [3]string{"", "/config/token", ""},
+		},
+		{
+			name:   "config credentials_helper over env token_file",
+			config: grackdbProviderModel{CredentialsHelper: types.StringValue("config-helper")},
+			env:    map[string]string{"GRACKDB_TOKEN_FILE": "/env/token"},
+			want:   [3]string{"", "", "config-helper"},
+		},
+		{
+			name:   "config token over env",
+			config: grackdbProviderModel{Token: types.StringValue("config-token")},
+			env:    map[string]string{"GRACKDB_TOKEN": "env-token", "GRACKDB_CREDENTIALS_HELPER": "env-helper"},
+			want:   [3]string{"config-token", "", ""},
+		},
+		{
+			name: "env token_file over profile token",
+			env:  map[string]string{"GRACKDB_TOKEN_FILE": "/env/token"},
+			want: [3]string{"", "/env/token", ""},
+		},
+		{
+			name: "env group",
+			env:  map[string]string{"GRACKDB_TOKEN": "env-token", "GRACKDB_TOKEN_FILE": "/env/token"},
+			want: [3]string{"env-token", "/env/token", ""},
+		},
+		{
+			name: "profile",
+			want: [3]string{"profile-token", "", ""},
+		},
+		{
+			name:    "selected profile",
+			profile: "helper",
+			want:    [3]string{"", "", "profile-helper"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(configFileEnv, configFile)
+			for _, env := range []string{"GRACKDB_PROFILE", "GRACKDB_TOKEN", "GRACKDB_TOKEN_FILE", "GRACKDB_CREDENTIALS_HELPER"} {
+				t.Setenv(env, tt.env[env])
+			}
+			if tt.profile != "" {
+				t.Setenv("GRACKDB_PROFILE", tt.profile)
+			}
+
+			config := tt.config
+			if diags := applyDefaults(&config); diags.HasError() {
+				t.Fatalf("applyDefaults returned errors: %v", diags)
+			}
+
+			got := [3]string{config.Token.ValueString(), config.TokenFile.ValueString(), config.CredentialsHelper.ValueString()}
+			if got != tt.want {
+				t.Errorf("token, token_file, credentials_helper = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoginSettings(t *testing.T) {
+	isolateEnvironment(t)
+
+	configFile := filepath.Join(t.TempDir(), "config.toml")
+	err := os.WriteFile(configFile, []byte(`
+[profiles.default]
+api_url = "https://default.example.com/query"
+
+[profiles.staging]
+api_url = "https://staging.example.com/query"
+`), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		configFile string
+		env        map[string]string
+		profile    string
+		apiUrl     string
+		want       string
+	}{
+		{name: "built in default", want: DefaultApiUrl},
+		{name: "flag over profile", configFile: configFile, apiUrl: "https://flag.example.com/query", want: "https://flag.example.com/query"},
+		{name: "default profile", configFile: configFile, want: "https://default.example.com/query"},
+		{name: "selected profile", configFile: configFile, profile: "staging", want: "https://staging.example.com/query"},
+		{
+			name:       "profile from env",
+			configFile: configFile,
+			env:        map[string]string{"GRACKDB_PROFILE": "staging"},
+			want:       "https://staging.example.com/query",
+		},
+		{
+			name:       "env over profile",
+			configFile: configFile,
+			env:        map[string]string{"GRACKDB_API_URL": "https://env.example.com/query"},
+			profile:    "staging",
+			want:       "https://env.example.com/query",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.configFile != "" {
+				t.Setenv(configFileEnv, tt.configFile)
+			}
+			for env, value := range tt.env {
+				t.Setenv(env, value)
+			}
+
+			got, _, err := LoginSettings(tt.profile, tt.apiUrl)
+			if err != nil {
+				t.Fatalf("LoginSettings(%q, %q) returned error %s", tt.profile, tt.apiUrl, err)
+			}
+			if got != tt.want {
+				t.Errorf("LoginSettings(%q, %q) = %q, want %q", tt.profile, tt.apiUrl, got, tt.want)
+			}
+		})
+	}
+
+	t.Run("undefined profile", func(t *testing.T) {
+		t.Setenv(configFileEnv, configFile)
+
+		if _, _, err := LoginSettings("missing", ""); err == nil {
+			t.Error("LoginSettings returned no error for an undefined profile")
+		}
+	})
+}
@@ -0,0 +1,111 @@
+package provider
+
+import (
+	"context"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	sdkresource "go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+const tracerName = "github.com/fogo-sh/terraform-provider-grackdb"
+
+// traceFlushTimeout bounds how long an operation waits for its spans to be exported.
+const traceFlushTimeout = 2 * time.Second
+
+// tracePropagator injects W3C traceparent headers into requests sent to GrackDB.
+var tracePropagator = propagation.TraceContext{}
+
+// tracingEnabledByEnv reports whether the standard OTEL_* environment variables request
+// trace export without any provider configuration.
+func tracingEnabledByEnv() bool {
+	if strings.EqualFold(os.Getenv("OTEL_SDK_DISABLED"), "true") {
+		return false
+	}
+
+	return os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") != "" || os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") != ""
+}
+
+// newTracerProvider builds the tracer provider used for provider operations, exporting spans
+// over OTLP/HTTP to endpoint, or to the endpoint configured by OTEL_* environment variables
+// when endpoint is empty. A no-op tracer provider is returned when tracing isn't configured.
+func newTracerProvider(ctx context.Context, endpoint string, version string) (trace.TracerProvider, error) {
+	if endpoint == "" && !tracingEnabledByEnv() {
+		return noop.NewTracerProvider(), nil
+	}
+
+	options := []otlptracehttp.Option{}
+	if endpoint != "" {
+		options = append(options, otlptracehttp.WithEndpointURL(endpoint))
+	}
+
+	exporter, err := otlptracehttp.New(ctx, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := sdkresource.New(
+		ctx,
+		sdkresource.WithAttributes(
+			semconv.ServiceName("terraform-provider-grackdb"),
+			semconv.ServiceVersion(version),
+		),
+		sdkresource.WithFromEnv(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// Spans are batched so a slow or unreachable collector doesn't hold up API requests, and
+	// flushed by endSpan once each operation completes.
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	), nil
+}
+
+// startSpan starts a span covering a single provider operation, such as a resource Create.
+func (c *apiClient) startSpan(ctx context.Context, name string) (context.Context, trace.Span) {
+	return c.tracerProvider.Tracer(tracerName).Start(ctx, name)
+}
+
+// endSpan ends span, marking it as failed if diags contains any errors, and flushes it to
+// the collector. Terraform can stop the provider process at any point after an operation
+// completes, so spans can't be left for the batcher to export later.
+func (c *apiClient) endSpan(ctx context.Context, span trace.Span, diags diag.Diagnostics) {
+	if diags.HasError() {
+		for _, d := range diags.Errors() {
+			span.AddEvent(d.Summary(), trace.WithAttributes(attribute.String("detail", d.Detail())))
+		}
+		span.SetStatus(codes.Error, diags.Errors()[0].Summary())
+	}
+
+	span.End()
+
+	flusher, ok := c.tracerProvider.(interface{ ForceFlush(context.Context) error })
+	if !ok {
+		return
+	}
+
+	// The operation's own context may already be cancelled, but its spans are still worth
+	// exporting.
+	flushCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), traceFlushTimeout)
+	defer cancel()
+
+	if err := flusher.ForceFlush(flushCtx); err != nil {
+		tflog.Warn(ctx, "Unable to export trace spans", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+}
@@ -0,0 +1,138 @@
+package provider
+
+import (
+	"bytes"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// createUser configures the provider against the GrackDB server at apiUrl, exporting spans to
+// otlpEndpoint, and creates a grackdb_user through it.
+func createUser(t *testing.T, apiUrl string, otlpEndpoint string) {
+	t.Helper()
+
+	provider := configureTestProvider(t, map[string]tftypes.Value{
+		"api_url":       tftypes.NewValue(tftypes.String, apiUrl),
+		"token":         tftypes.NewValue(tftypes.String, "token"),
+		"otlp_endpoint": tftypes.NewValue(tftypes.String, otlpEndpoint),
+	})
+
+	userType := provider.resourceType("grackdb_user")
+	provider.applyResource(t, "grackdb_user", tftypes.NewValue(userType, nil), objectValue(userType, map[string]tftypes.Value{
+		"username": tftypes.NewValue(tftypes.String, "bob"),
+	}))
+}
+
+func TestTracing(t *testing.T) {
+	isolateEnvironment(t)
+
+	var mu sync.Mutex
+	var spans []*tracepb.Span
+	var traceparents []string
+
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/traces" {
+			t.Errorf("collector received request for %s, want /v1/traces", r.URL.Path)
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Errorf("unable to read export request: %s", err)
+			return
+		}
+		var export coltracepb.ExportTraceServiceRequest
+		if err := proto.Unmarshal(body, &export); err != nil {
+			t.Errorf("unable to decode export request: %s", err)
+			return
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		for _, resourceSpans := range export.ResourceSpans {
+			for _, scopeSpans := range resourceSpans.ScopeSpans {
+				spans = append(spans, scopeSpans.Spans...)
+			}
+		}
+	}))
+	defer collector.Close()
+
+	grackdb := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		traceparents = append(traceparents, r.Header.Get("Traceparent"))
+		mu.Unlock()
+
+		w.Write([]byte(`{"data": {"createUser": {"__typename": "User", "id": "u1", "username": "bob", "avatarUrl": null}}}`))
+	}))
+	defer grackdb.Close()
+
+	createUser(t, grackdb.URL, collector.URL+"/v1/traces")
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	var createSpan, httpSpan *tracepb.Span
+	for _, span := range spans {
+		if span.Name == "grackdb_user.Create" {
+			createSpan = span
+		}
+	}
+	if createSpan == nil {
+		t.Fatalf("no grackdb_user.Create span among %d exported", len(spans))
+	}
+	for _, span := range spans {
+		if span.Kind == tracepb.Span_SPAN_KIND_CLIENT && bytes.Equal(span.ParentSpanId, createSpan.SpanId) {
+			httpSpan = span
+		}
+	}
+	if httpSpan == nil {
+		t.Fatal("grackdb_user.Create span has no HTTP client span as a child")
+	}
+
+	if len(traceparents) != 1 {
+		t.Fatalf("GrackDB received %d requests, want 1", len(traceparents))
+	}
+	// traceparent is version-traceid-parentid-flags, where parentid is the HTTP client span.
+	fields := strings.Split(traceparents[0], "-")
+	if len(fields) != 4 {
+		t.Fatalf("GrackDB received traceparent %q, want a W3C trace context", traceparents[0])
+	}
+	if want := hex.EncodeToString(createSpan.TraceId); fields[1] != want {
+		t.Errorf("traceparent trace ID = %s, want %s", fields[1], want)
+	}
+	if want := hex.EncodeToString(httpSpan.SpanId); fields[2] != want {
+		t.Errorf("traceparent parent ID = %s, want %s", fields[2], want)
+	}
+}
+
+func TestTracingUnresponsiveCollector(t *testing.T) {
+	isolateEnvironment(t)
+
+	released := make(chan struct{})
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-released
+	}))
+	defer collector.Close()
+	defer close(released)
+
+	grackdb := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"data": {"createUser": {"__typename": "User", "id": "u1", "username": "bob", "avatarUrl": null}}}`))
+	}))
+	defer grackdb.Close()
+
+	start := time.Now()
+	createUser(t, grackdb.URL, collector.URL+"/v1/traces")
+	if elapsed := time.Since(start); elapsed > 2*traceFlushTimeout {
+		t.Errorf("creating a user took %s with an unresponsive collector, want at most %s", elapsed, 2*traceFlushTimeout)
+	}
+}
@@ -0,0 +1,225 @@
+package provider
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+)
+
+// unixSocketHost is the placeholder host requests sent over a unix socket are addressed to.
+const unixSocketHost = "localhost"
+
+// unixSocketDefaultPath is the GraphQL endpoint path used for unix:// API URLs, which can't
+// include one.
+const unixSocketDefaultPath = "/query"
+
+// parseUnixSocketURL splits API URLs using the unix:// and http+unix:// schemes into the URL
+// requests are sent to and the path of the socket they're sent over. unix:///path/to.sock
+// sends requests to unixSocketDefaultPath, while http+unix:// URLs take the socket path
+// percent-encoded as their host, followed by the endpoint path, as in
+// http+unix://%2Fpath%2Fto.sock/query. Other URLs are returned unchanged with no socket path.
+func parseUnixSocketURL(apiUrl string) (string, string, error) {
+	switch {
+	case strings.HasPrefix(apiUrl, "http+unix://"):
+		rest := strings.TrimPrefix(apiUrl, "http+unix://")
+		encodedSocket, requestPath := rest, "/"
+		if i := strings.IndexAny(rest, "/?"); i != -1 {
+			encodedSocket, requestPath = rest[:i], rest[i:]
+			if !strings.HasPrefix(requestPath, "/") {
+				requestPath = "/" + requestPath
+			}
+		}
+
+		socketPath, err := url.PathUnescape(encodedSocket)
+		if err != nil {
+			return "", "", fmt.Errorf("invalid socket path: %w", err)
+		}
+		if socketPath == "" {
+			return "", "", fmt.Errorf("%q doesn't include a socket path", apiUrl)
+		}
+
+		return "http://" + unixSocketHost + requestPath, socketPath, nil
+	case strings.HasPrefix(apiUrl, "unix:"):
+		parsed, err := url.Parse(apiUrl)
+		if err != nil {
+			return "", "", err
+		}
+
+		socketPath := parsed.Host + parsed.Path
+		if parsed.Opaque != "" {
+			socketPath = parsed.Opaque
+		}
+		if socketPath == "" {
+			return "", "", fmt.Errorf("%q doesn't include a socket path", apiUrl)
+		}
+
+		return "http://" + unixSocketHost + unixSocketDefaultPath, socketPath, nil
+	default:
+		return apiUrl, "", nil
+	}
+}
+
+// newBaseTransport builds the transport API requests are ultimately sent over, applying the
+// TLS and proxy settings from config. When socketPath is set every connection is made to that
+// unix socket instead.
+func newBaseTransport(config grackdbProviderModel, socketPath string) (*http.Transport, diag.Diagnostics) {
+	var diags diag.Diagnostics
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	transport.TLSClientConfig = tlsConfig
+
+	if !config.CaCertPem.IsNull() || !config.CaCertFile.IsNull() {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+
+		if !config.CaCertPem.IsNull() && !pool.AppendCertsFromPEM([]byte(config.CaCertPem.ValueString())) {
+			diags.AddAttributeError(
+				path.Root("ca_cert_pem"),
+				"Invalid CA certificate",
+				"No PEM encoded certificates were found.",
+			)
+		}
+
+		if !config.CaCertFile.IsNull() {
+			contents, err := os.ReadFile(config.CaCertFile.ValueString())
+			if err != nil {
+				diags.AddAttributeError(
+					path.Root("ca_cert_file"),
+					"Unable to read CA certificate",
+					err.Error(),
+				)
+			} else if !pool.AppendCertsFromPEM(contents) {
+				diags.AddAttributeError(
+					path.Root("ca_cert_file"),
+					"Invalid CA certificate",
+					fmt.Sprintf("No PEM encoded certificates were found in %s.", config.CaCertFile.ValueString()),
+				)
+			}
+		}
+
+		tlsConfig.RootCAs = pool
+	}
+
+	if config.ClientCert.IsNull() != config.ClientKey.IsNull() {
+		diags.AddAttributeError(
+			path.Root("client_cert"),
+			"Incomplete client certificate",
+			"client_cert and client_key must be set together.",
+		)
+	} else if !config.ClientCert.IsNull() {
+		cert, err := tls.X509KeyPair([]byte(config.ClientCert.ValueString()), []byte(config.ClientKey.ValueString()))
+		if err != nil {
+			diags.AddAttributeError(
+				path.Root("client_cert"),
+				"Invalid client certificate",
+				err.Error(),
+			)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	if config.InsecureSkipVerify.ValueBool() {
+		diags.AddAttributeWarning(
+			path.Root("insecure_skip_verify"),
+			"TLS certificate verification disabled",
+			"The GrackDB server's certificate won't be verified, leaving API requests open to interception. This should only be used for testing.",
+		)
+		tlsConfig.InsecureSkipVerify = true
+	}
+
+	if !config.ProxyUrl.IsNull() {
+		proxyUrl, err := url.Parse(config.ProxyUrl.ValueString())
+		if err != nil || proxyUrl.Scheme == "" || proxyUrl.Host == "" {
+			diags.AddAttributeError(
+				path.Root("proxy_url"),
+				"Invalid proxy URL",
+				fmt.Sprintf("%q is not an absolute URL, such as http://proxy.example.com:3128.", config.ProxyUrl.ValueString()),
+			)
+		} else {
+			transport.Proxy = http.ProxyURL(proxyUrl)
+		}
+	}
+
+	if socketPath != "" {
+		if !config.ProxyUrl.IsNull() {
+			diags.AddAttributeWarning(
+				path.Root("proxy_url"),
+				"Proxy ignored",
+				"Requests to a unix socket api_url aren't sent through a proxy.",
+			)
+		}
+		transport.Proxy = nil
+
+		dialer := &net.Dialer{Timeout: 30 * time.Second}
+		transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return dialer.DialContext(ctx, "unix", socketPath)
+		}
+	}
+
+	return transport, diags
+}
+
+// compressionThreshold is the size in bytes above which request bodies are gzipped.
+const compressionThreshold = 1024
+
+type withCompressionType struct {
+	rt               http.RoundTripper
+	compressRequests bool
+}
+
+// withCompression gzips request bodies larger than compressionThreshold when compressRequests
+// is set. Responses are left to net/http, which requests and transparently decompresses gzip.
+func withCompression(rt http.RoundTripper, compressRequests bool) withCompressionType {
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+
+	return withCompressionType{rt: rt, compressRequests: compressRequests}
+}
+
+func (c withCompressionType) RoundTrip(req *http.Request) (*http.Response, error) {
+	if c.compressRequests && req.Body != nil && req.ContentLength > compressionThreshold {
+		req = req.Clone(req.Context())
+		body, err := ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		compressed := new(bytes.Buffer)
+		writer := gzip.NewWriter(compressed)
+		if _, err := writer.Write(body); err != nil {
+			return nil, err
+		}
+		if err := writer.Close(); err != nil {
+			return nil, err
+		}
+
+		compressedBody := compressed.Bytes()
+		req.Body = ioutil.NopCloser(bytes.NewReader(compressedBody))
+		req.GetBody = func() (io.ReadCloser, error) {
+			return ioutil.NopCloser(bytes.NewReader(compressedBody)), nil
+		}
+		req.ContentLength = int64(len(compressedBody))
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+
+	return c.rt.RoundTrip(req)
+}
@@ -0,0 +1,242 @@
+package provider
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+)
+
+func TestParseUnixSocketURL(t *testing.T) {
+	tests := []struct {
+		name           string
+		apiUrl         string
+		wantRequestUrl string
+		wantSocketPath string
+		wantErr        bool
+	}{
+		{name: "https", apiUrl: "https://grackdb.fogo.sh/query", wantRequestUrl: "https://grackdb.fogo.sh/query"},
+		{name: "unix relative", apiUrl: "unix:grackdb.sock", wantRequestUrl: "http://localhost/query", wantSocketPath: "grackdb.sock"},
+		{name: "unix absolute", apiUrl: "unix:///run/grackdb/grackdb.sock", wantRequestUrl: "http://localhost/query", wantSocketPath: "/run/grackdb/grackdb.sock"},
+		{name: "unix without socket", apiUrl: "unix://", wantErr: true},
+		{
+			name:           "http+unix with path",
+			apiUrl:         "http+unix://%2Frun%2Fgrackdb.sock/graphql",
+			wantRequestUrl: "http://localhost/graphql",
+			wantSocketPath: "/run/grackdb.sock",
+		},
+		{
+			name:           "http+unix with query",
+			apiUrl:         "http+unix://%2Frun%2Fgrackdb.sock/graphql?tenant=fogo",
+			wantRequestUrl: "http://localhost/graphql?tenant=fogo",
+			wantSocketPath: "/run/grackdb.sock",
+		},
+		{
+			name:           "http+unix with query and no path",
+			apiUrl:         "http+unix://%2Frun%2Fgrackdb.sock?tenant=fogo",
+			wantRequestUrl: "http://localhost/?tenant=fogo",
+			wantSocketPath: "/run/grackdb.sock",
+		},
+		{name: "http+unix without path", apiUrl: "http+unix://%2Frun%2Fgrackdb.sock", wantRequestUrl: "http://localhost/", wantSocketPath: "/run/grackdb.sock"},
+		{name: "http+unix without socket", apiUrl: "http+unix:///query", wantErr: true},
+		{name: "http+unix invalid escape", apiUrl: "http+unix://%zz/query", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requestUrl, socketPath, err := parseUnixSocketURL(tt.apiUrl)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("parseUnixSocketURL(%q) = %q, %q, want an error", tt.apiUrl, requestUrl, socketPath)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseUnixSocketURL(%q) returned error %s", tt.apiUrl, err)
+			}
+			if requestUrl != tt.wantRequestUrl || socketPath != tt.wantSocketPath {
+				t.Errorf("parseUnixSocketURL(%q) = %q, %q, want %q, %q", tt.apiUrl, requestUrl, socketPath, tt.wantRequestUrl, tt.wantSocketPath)
+			}
+		})
+	}
+}
+
+func TestUnixSocketRoundTrip(t *testing.T) {
+	socketPath := filepath.Join(t.TempDir(), "grackdb.sock")
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		t.Skipf("unix sockets unavailable: %s", err)
+	}
+
+	var gotPath string
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.RequestURI()
+		w.Write([]byte(`{"data": {"currentUser": {"__typename": "User", "id": "u1", "username": "bob", "avatarUrl": null}}}`))
+	}))
+	server.Listener = listener
+	server.Start()
+	defer server.Close()
+
+	tests := []struct {
+		name     string
+		apiUrl   string
+		wantPath string
+	}{
+		{name: "unix", apiUrl: "unix://" + socketPath, wantPath: "/query"},
+		{name: "http+unix", apiUrl: "http+unix://" + url.PathEscape(socketPath) + "/graphql?tenant=fogo", wantPath: "/graphql?tenant=fogo"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			isolateEnvironment(t)
+			gotPath = ""
+
+			// Validating credentials looks up the current user through the socket.
+			configureTestProvider(t, map[string]tftypes.Value{
+				"api_url":              tftypes.NewValue(tftypes.String, tt.apiUrl),
+				"token":                tftypes.NewValue(tftypes.String, "token"),
+				"validate_credentials": tftypes.NewValue(tftypes.Bool, true),
+			})
+
+			if gotPath != tt.wantPath {
+				t.Errorf("GrackDB received a request for %q, want %q", gotPath, tt.wantPath)
+			}
+		})
+	}
+}
+
+func TestWithCompressionRequests(t *testing.T) {
+	large := strings.Repeat("a", compressionThreshold+1)
+	small := strings.Repeat("a", compressionThreshold)
+
+	tests := []struct {
+		name             string
+		compressRequests bool
+		body             string
+		wantCompressed   bool
+	}{
+		{name: "over threshold", compressRequests: true, body: large, wantCompressed: true},
+		{name: "at threshold", compressRequests: true, body: small, wantCompressed: false},
+		{name: "disabled", compressRequests: false, body: large, wantCompressed: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotEncoding, gotAcceptEncoding string
+			var gotBody []byte
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotEncoding = r.Header.Get("Content-Encoding")
+				gotAcceptEncoding = r.Header.Get("Accept-Encoding")
+
+				var body io.Reader = r.Body
+				if gotEncoding == "gzip" {
+					reader, err := gzip.NewReader(r.Body)
+					if err != nil {
+						t.Errorf("request body is not gzipped: %s", err)
+						return
+					}
+					body = reader
+				}
+
+				var err error
+				gotBody, err = io.ReadAll(body)
+				if err != nil {
+					t.Errorf("unable to read request body: %s", err)
+				}
+			}))
+			defer server.Close()
+
+			client := &http.Client{Transport: withCompression(nil, tt.compressRequests)}
+			resp, err := client.Post(server.URL, "application/json", strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatalf("request failed: %s", err)
+			}
+			resp.Body.Close()
+
+			if compressed := gotEncoding == "gzip"; compressed != tt.wantCompressed {
+				t.Errorf("Content-Encoding = %q, want compressed %v", gotEncoding, tt.wantCompressed)
+			}
+			if gotAcceptEncoding != "gzip" {
+				t.Errorf("Accept-Encoding = %q, want gzip", gotAcceptEncoding)
+			}
+			if string(gotBody) != tt.body {
+				t.Errorf("server received %d bytes, want the %d sent", len(gotBody), len(tt.body))
+			}
+		})
+	}
+}
+
+func TestWithCompressionResponses(t *testing.T) {
+	const payload = `{"data":{"users":[]}}`
+
+	var compressed bytes.Buffer
+	writer := gzip.NewWriter(&compressed)
+	writer.Write([]byte(payload))
+	writer.Close()
+
+	tests := []struct {
+		name     string
+		encoding string
+		body     []byte
+		want     string
+	}{
+		{name: "gzipped", encoding: "gzip", body: compressed.Bytes(), want: payload},
+		{name: "gzipped empty body", encoding: "gzip", body: nil, want: ""},
+		{name: "identity", encoding: "", body: []byte(payload), want: payload},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.encoding != "" {
+					w.Header().Set("Content-Encoding", tt.encoding)
+				}
+				w.Write(tt.body)
+			}))
+			defer server.Close()
+
+			// Responses are decompressed by net/http, which nothing in the provider's transport
+			// should disable.
+			baseTransport, diags := newBaseTransport(grackdbProviderModel{}, "")
+			if diags.HasError() {
+				t.Fatalf("unable to build transport: %v", diags)
+			}
+			client := &http.Client{Transport: withCompression(baseTransport, false)}
+			resp, err := client.Get(server.URL)
+			if err != nil {
+				t.Fatalf("request failed: %s", err)
+			}
+			defer resp.Body.Close()
+
+			body, err := io.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatalf("unable to read response body: %s", err)
+			}
+			if string(body) != tt.want {
+				t.Errorf("body = %q, want %q", body, tt.want)
+			}
+
+			// net/http leaves the headers of empty bodies alone, as there's nothing to decompress.
+			if tt.encoding == "" || len(tt.body) == 0 {
+				return
+			}
+			if encoding := resp.Header.Get("Content-Encoding"); encoding != "" {
+				t.Errorf("Content-Encoding = %q, want it removed", encoding)
+			}
+			if length := resp.Header.Get("Content-Length"); length != "" {
+				t.Errorf("Content-Length = %q, want it removed", length)
+			}
+			if !resp.Uncompressed {
+				t.Error("Uncompressed = false, want true")
+			}
+		})
+	}
+}
@@ -10,6 +10,26 @@ type DiscordAccount struct {
 	DiscordID     string      `json:"discordId"`
 	Username      string      `json:"username"`
 	Discriminator string      `json:"discriminator"`
+	GlobalName    *string     `json:"globalName"`
 	Owner         *User       `json:"owner"`
 	Bot           *DiscordBot `json:"bot"`
 }
+
+// DiscordAccountFragment selects every DiscordAccount field exposed by the provider.
+// Queries using it should spread ...DiscordAccountFields and append this fragment to their document.
+const DiscordAccountFragment = `
+	fragment DiscordAccountFields on DiscordAccount {
+		__typename
+		id
+		discordId
+		username
+		discriminator
+		globalName
+		owner {
+			...UserFields
+		}
+		bot {
+			id
+		}
+	}
+` + UserFragment
@@ -5,3 +5,14 @@ type User struct {
 	Username  string  `json:"username"`
 	AvatarURL *string `json:"avatarUrl"`
 }
+
+// UserFragment selects every User field exposed by the provider.
+// Queries using it should spread ...UserFields and append this fragment to their document.
+const UserFragment = `
+	fragment UserFields on User {
+		__typename
+		id
+		username
+		avatarUrl
+	}
+`
@@ -4,9 +4,14 @@ import (
 	"context"
 	"flag"
 	"log"
+	"os"
+	"os/signal"
+	"time"
 
+	"github.com/fogo-sh/terraform-provider-grackdb/internal/auth"
 	"github.com/fogo-sh/terraform-provider-grackdb/internal/provider"
-	"github.com/hashicorp/terraform-plugin-sdk/v2/plugin"
+	"github.com/hashicorp/terraform-plugin-framework/providerserver"
+	"golang.org/x/oauth2"
 )
 
 // Run "go generate" to format example terraform files and generate the docs for the registry/website
@@ -19,6 +24,9 @@ import (
 // can be customized.
 //go:generate go run github.com/hashicorp/terraform-plugin-docs/cmd/tfplugindocs
 
+// Write the manifest of GraphQL operations used for allow-listing persisted queries on the server.
+//go:generate go run ./tools/persistedqueries -output persisted-queries.json
+
 var (
 	// these will be set by the goreleaser configuration
 	// to appropriate values for the compiled binary
@@ -29,21 +37,51 @@ var (
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "login" {
+		if err := login(os.Args[2:]); err != nil {
+			log.Fatal(err.Error())
+		}
+		return
+	}
+
 	var debugMode bool
 
 	flag.BoolVar(&debugMode, "debug", false, "set to true to run the provider with support for debuggers like delve")
 	flag.Parse()
 
-	opts := &plugin.ServeOpts{ProviderFunc: provider.New(version)}
+	opts := providerserver.ServeOpts{
+		Address: "registry.terraform.io/nint8835/grackdb",
+		Debug:   debugMode,
+	}
 
-	if debugMode {
-		// TODO: update this string with the full name of your provider as used in your configs
-		err := plugin.Debug(context.Background(), "registry.terraform.io/nint8835/grackdb", opts)
-		if err != nil {
-			log.Fatal(err.Error())
-		}
-		return
+	err := providerserver.Serve(context.Background(), provider.New(version), opts)
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+}
+
+// login implements the login subcommand, obtaining a token for the provider to use by default.
+func login(args []string) error {
+	var apiUrl, profile, identityProvider string
+	var timeout time.Duration
+
+	flags := flag.NewFlagSet("login", flag.ExitOnError)
+	flags.StringVar(&apiUrl, "api-url", "", "URL of the GrackDB GraphQL endpoint to log in to (default GRACKDB_API_URL, the profile's api_url or "+provider.DefaultApiUrl+")")
+	flags.StringVar(&profile, "profile", "", "profile to read the default API URL and TLS and proxy settings from (default GRACKDB_PROFILE or the default profile)")
+	flags.StringVar(&identityProvider, "provider", "", "service to log in with, such as discord or github")
+	flags.DurationVar(&timeout, "timeout", 5*time.Minute, "how long to wait for the login to complete")
+	flags.Parse(args)
+
+	apiUrl, httpClient, err := provider.LoginSettings(profile, apiUrl)
+	if err != nil {
+		return err
 	}
 
-	plugin.Serve(opts)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	ctx = context.WithValue(ctx, oauth2.HTTPClient, httpClient)
+
+	return auth.Login(ctx, apiUrl, identityProvider, os.Stdout)
 }
@@ -0,0 +1,28 @@
+// Command persistedqueries writes a manifest of every GraphQL operation the provider sends,
+// for allow-listing them on a GrackDB server that only accepts persisted queries.
+package main
+
+import (
+	"flag"
+	"io/ioutil"
+	"log"
+
+	"github.com/fogo-sh/terraform-provider-grackdb/internal/provider"
+)
+
+func main() {
+	var output string
+
+	flag.StringVar(&output, "output", "persisted-queries.json", "path to write the manifest to")
+	flag.Parse()
+
+	manifest, err := provider.PersistedQueryManifest()
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+
+	err = ioutil.WriteFile(output, append(manifest, '\n'), 0644)
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+}